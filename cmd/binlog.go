@@ -0,0 +1,291 @@
+package cmd
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "time"
+
+    "github.com/go-mysql-org/go-mysql/mysql"
+    "github.com/go-mysql-org/go-mysql/replication"
+    "github.com/siddontang/go-log/log"
+)
+
+// binlog 连接保活: 服务器按心跳周期发送心跳事件，超过读超时未收到任何事件视为断开并重连。
+const (
+    binlogHeartbeatPeriod      = 30 * time.Second
+    binlogReadTimeout          = 90 * time.Second
+    binlogMaxReconnectAttempts = 10
+)
+
+// BinlogEventHeader binlog 事件头。
+type BinlogEventHeader struct {
+    Timestamp uint32
+    EventType byte
+    ServerID  uint32
+    LogPos    uint32
+}
+
+// BinlogEvent binlog 事件，Event 为 *RotateEvent、*QueryEvent、*XIDEvent、*TableMapEvent、*RowsEvent 之一，其他事件为 nil。
+type BinlogEvent struct {
+    Header BinlogEventHeader
+    Event  any
+}
+
+// RotateEvent 切换 binlog 文件。
+type RotateEvent struct {
+    Position uint64
+    File     string
+}
+
+// QueryEvent 语句事件。(BEGIN、COMMIT、DDL)
+type QueryEvent struct {
+    Schema string
+    Query  string
+}
+
+// XIDEvent 事务提交。
+type XIDEvent struct {
+    XID uint64
+}
+
+// TableMapEvent 表结构映射。
+type TableMapEvent struct {
+    TableID     uint64
+    Schema      string
+    Table       string
+    ColumnTypes []byte
+    ColumnMeta  []uint16
+}
+
+// RowsEvent 行变更事件，Before/After 中未记录的字段（见 BeforePresent/AfterPresent）为 nil。
+// INSERT 仅有 After，DELETE 仅有 Before，UPDATE 两者一一对应。
+// 整数为 int64，字符串、BLOB、BIT 为 []byte，DECIMAL、日期时间、JSON 为字符串，ENUM、SET 为 binlogEnum、binlogSet。
+type RowsEvent struct {
+    Table         *TableMapEvent
+    Before        [][]any
+    After         [][]any
+    BeforePresent []bool
+    AfterPresent  []bool
+}
+
+// binlogEnum ENUM 字段值（成员序号，从 1 开始）。
+type binlogEnum uint64
+
+// binlogSet SET 字段值（成员位图）。
+type binlogSet uint64
+
+// BinlogStreamer binlog 事件流。
+type BinlogStreamer interface {
+    // GetEvent 读取下一个事件。
+    GetEvent() (*BinlogEvent, error)
+    // Close 关闭事件流。
+    Close() error
+}
+
+// BinlogSyncer 以从库身份读取 MySQL 行格式 binlog。(go-mysql)
+type BinlogSyncer struct {
+    syncer   *replication.BinlogSyncer
+    streamer *replication.BinlogStreamer
+    pending  []*BinlogEvent
+}
+
+// NewBinlogSyncer 连接 MySQL 服务器，从 file:position 开始读取 binlog。
+// location 为 TIMESTAMP 字段转换时区（与导出连接的会话时区一致）。
+func NewBinlogSyncer(serverDbConfig *DbConfig, serverID uint32, file string, position uint32, location *time.Location) (*BinlogSyncer, error) {
+    handler, err := log.NewStreamHandler(os.Stderr)
+    if err != nil {
+        return nil, err
+    }
+    logger := log.NewDefault(handler)
+    logger.SetLevel(log.LevelWarn)
+
+    syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+        ServerID:                serverID,
+        Flavor:                  mysql.MySQLFlavor,
+        Host:                    serverDbConfig.Host,
+        Port:                    uint16(serverDbConfig.Port),
+        User:                    serverDbConfig.User,
+        Password:                serverDbConfig.Password,
+        Charset:                 serverDbConfig.Charset,
+        TimestampStringLocation: location,
+        HeartbeatPeriod:         binlogHeartbeatPeriod,
+        ReadTimeout:             binlogReadTimeout,
+        MaxReconnectAttempts:    binlogMaxReconnectAttempts,
+        Logger:                  logger,
+    })
+    streamer, err := syncer.StartSync(mysql.Position{Name: file, Pos: position})
+    if err != nil {
+        syncer.Close()
+        return nil, err
+    }
+    return &BinlogSyncer{syncer: syncer, streamer: streamer}, nil
+}
+
+// GetEvent 读取下一个事件，压缩事务展开为其中的各个事件。
+func (s *BinlogSyncer) GetEvent() (*BinlogEvent, error) {
+    for len(s.pending) == 0 {
+        ev, err := s.streamer.GetEvent(context.Background())
+        if err != nil {
+            return nil, err
+        }
+        if s.pending, err = getBinlogEvents(ev, ev.Header.LogPos); err != nil {
+            return nil, err
+        }
+    }
+    event := s.pending[0]
+    s.pending = s.pending[1:]
+    return event, nil
+}
+
+// Close 关闭连接。
+func (s *BinlogSyncer) Close() error {
+    s.syncer.Close()
+    return nil
+}
+
+// getBinlogEvents go-mysql 事件转换为 binlog 事件，logPos 为事件结束位置。
+// 压缩事务中的事件不单独记录位置，均使用压缩事务的结束位置。
+func getBinlogEvents(ev *replication.BinlogEvent, logPos uint32) ([]*BinlogEvent, error) {
+    event := &BinlogEvent{Header: BinlogEventHeader{
+        Timestamp: ev.Header.Timestamp,
+        EventType: byte(ev.Header.EventType),
+        ServerID:  ev.Header.ServerID,
+        LogPos:    logPos,
+    }}
+
+    switch e := ev.Event.(type) {
+    case *replication.RotateEvent:
+        event.Event = &RotateEvent{Position: e.Position, File: string(e.NextLogName)}
+    case *replication.QueryEvent:
+        event.Event = &QueryEvent{Schema: string(e.Schema), Query: string(e.Query)}
+    case *replication.XIDEvent:
+        event.Event = &XIDEvent{XID: e.XID}
+    case *replication.TableMapEvent:
+        event.Event = getTableMapEvent(e)
+    case *replication.RowsEvent:
+        if ev.Header.EventType == replication.PARTIAL_UPDATE_ROWS_EVENT {
+            return nil, errors.New("不支持 JSON 部分更新事件，请设置 binlog_row_value_options = ''")
+        }
+        rowsEvent, err := getRowsEvent(ev.Header.EventType, e)
+        if err != nil {
+            return nil, err
+        }
+        event.Event = rowsEvent
+    case *replication.TransactionPayloadEvent:
+        var events []*BinlogEvent
+        for _, payloadEvent := range e.Events {
+            payloadEvents, err := getBinlogEvents(payloadEvent, logPos)
+            if err != nil {
+                return nil, err
+            }
+            events = append(events, payloadEvents...)
+        }
+        return events, nil
+    }
+    return []*BinlogEvent{event}, nil
+}
+
+// getTableMapEvent 表结构映射。
+func getTableMapEvent(e *replication.TableMapEvent) *TableMapEvent {
+    return &TableMapEvent{
+        TableID:     e.TableID,
+        Schema:      string(e.Schema),
+        Table:       string(e.Table),
+        ColumnTypes: e.ColumnType,
+        ColumnMeta:  e.ColumnMeta,
+    }
+}
+
+// getRowsEvent 行变更事件，UPDATE 的行镜像按更新前、更新后交替排列。
+func getRowsEvent(eventType replication.EventType, e *replication.RowsEvent) (*RowsEvent, error) {
+    columnCount := len(e.Table.ColumnType)
+    rowsEvent := &RowsEvent{Table: getTableMapEvent(e.Table)}
+
+    var rows [][]any
+    for _, row := range e.Rows {
+        if len(row) != columnCount {
+            return nil, fmt.Errorf("表 `%s` binlog 行镜像字段数 %d 与表结构映射 %d 不一致", e.Table.Table, len(row), columnCount)
+        }
+        values := make([]any, columnCount)
+        for i, value := range row {
+            values[i] = getBinlogValue(e.Table, i, value)
+        }
+        rows = append(rows, values)
+    }
+
+    switch eventType {
+    case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+        rowsEvent.After, rowsEvent.AfterPresent = rows, getPresent(e.ColumnBitmap1, columnCount)
+    case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+        rowsEvent.Before, rowsEvent.BeforePresent = rows, getPresent(e.ColumnBitmap1, columnCount)
+    case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+        if len(rows)%2 != 0 {
+            return nil, fmt.Errorf("表 `%s` binlog 更新行镜像不成对", e.Table.Table)
+        }
+        for i := 0; i < len(rows); i += 2 {
+            rowsEvent.Before = append(rowsEvent.Before, rows[i])
+            rowsEvent.After = append(rowsEvent.After, rows[i+1])
+        }
+        rowsEvent.BeforePresent = getPresent(e.ColumnBitmap1, columnCount)
+        rowsEvent.AfterPresent = getPresent(e.ColumnBitmap2, columnCount)
+    default:
+        return nil, fmt.Errorf("不支持 binlog 行事件 %s", eventType)
+    }
+    return rowsEvent, nil
+}
+
+// getPresent 字段位图，未记录位图时为全部字段。
+func getPresent(bitmap []byte, columnCount int) []bool {
+    present := make([]bool, columnCount)
+    for i := range present {
+        present[i] = bitmap == nil || i/8 < len(bitmap) && bitmap[i/8]&(1<<(i%8)) != 0
+    }
+    return present
+}
+
+// getBinlogValue go-mysql 字段值转换为行变更事件的字段值类型。
+// 字符串、BLOB 复制一份，不引用 go-mysql 的数据包缓冲。
+func getBinlogValue(tableMap *replication.TableMapEvent, i int, value any) any {
+    switch v := value.(type) {
+    case int8:
+        return int64(v)
+    case int16:
+        return int64(v)
+    case int32:
+        return int64(v)
+    case int:
+        return int64(v)
+    case int64:
+        switch {
+        case tableMap.IsEnumColumn(i):
+            return binlogEnum(v)
+        case tableMap.IsSetColumn(i):
+            return binlogSet(v)
+        case tableMap.ColumnType[i] == mysql.MYSQL_TYPE_BIT:
+            // 与全量导出一致: BIT(M) 按 (M+7)/8 字节大端序。
+            meta := tableMap.ColumnMeta[i]
+            bs := make([]byte, (int(meta>>8)*8+int(meta&0xff)+7)/8)
+            for j := len(bs) - 1; j >= 0; j-- {
+                bs[j], v = byte(v), v>>8
+            }
+            return bs
+        }
+        return v
+    case float32:
+        return float64(v)
+    case string:
+        if tableMap.IsCharacterColumn(i) {
+            return []byte(v)
+        }
+        return string([]byte(v))
+    case []byte:
+        if tableMap.ColumnType[i] == mysql.MYSQL_TYPE_JSON {
+            // 空 JSON 文档（非严格模式写入的空值）按 JSON null。
+            return "null"
+        }
+        return append([]byte{}, v...)
+    }
+    return value
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+    "reflect"
+    "testing"
+
+    "github.com/go-mysql-org/go-mysql/mysql"
+    "github.com/go-mysql-org/go-mysql/replication"
+)
+
+func TestGetBinlogValue(t *testing.T) {
+    tableMap := &replication.TableMapEvent{
+        ColumnType: []byte{
+            mysql.MYSQL_TYPE_TINY, mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_YEAR, mysql.MYSQL_TYPE_FLOAT,
+            mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_NEWDECIMAL, mysql.MYSQL_TYPE_STRING, mysql.MYSQL_TYPE_STRING,
+            mysql.MYSQL_TYPE_BIT, mysql.MYSQL_TYPE_JSON, mysql.MYSQL_TYPE_BLOB,
+        },
+        ColumnMeta: []uint16{
+            0, 0, 0, 4,
+            256, 10<<8 | 2, uint16(mysql.MYSQL_TYPE_ENUM)<<8 | 1, uint16(mysql.MYSQL_TYPE_SET)<<8 | 1,
+            1<<8 | 2, 4, 2,
+        },
+    }
+
+    tests := []struct {
+        name  string
+        i     int
+        value any
+        want  any
+    }{
+        {name: "tinyint", i: 0, value: int8(-1), want: int64(-1)},
+        {name: "int", i: 1, value: int32(-2), want: int64(-2)},
+        {name: "year", i: 2, value: 2024, want: int64(2024)},
+        {name: "float", i: 3, value: float32(1.5), want: float64(1.5)},
+        {name: "varchar", i: 4, value: "a", want: []byte("a")},
+        {name: "decimal", i: 5, value: "1.50", want: "1.50"},
+        {name: "enum", i: 6, value: int64(2), want: binlogEnum(2)},
+        {name: "set", i: 7, value: int64(5), want: binlogSet(5)},
+        {name: "bit", i: 8, value: int64(0x3ff), want: []byte{0x03, 0xff}},
+        {name: "json", i: 9, value: `{"k":1}`, want: `{"k":1}`},
+        {name: "json empty", i: 9, value: []byte{}, want: "null"},
+        {name: "blob", i: 10, value: []byte{1, 2}, want: []byte{1, 2}},
+        {name: "nil", i: 1, value: nil, want: nil},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := getBinlogValue(tableMap, tt.i, tt.value); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("getBinlogValue() = %#v, want %#v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestGetBinlogEvents(t *testing.T) {
+    tableMap := &replication.TableMapEvent{
+        TableID:    1,
+        Schema:     []byte("game"),
+        Table:      []byte("player"),
+        ColumnType: []byte{mysql.MYSQL_TYPE_LONG, mysql.MYSQL_TYPE_VARCHAR},
+        ColumnMeta: []uint16{0, 256},
+    }
+    newEvent := func(eventType replication.EventType, logPos uint32, e replication.Event) *replication.BinlogEvent {
+        return &replication.BinlogEvent{Header: &replication.EventHeader{EventType: eventType, LogPos: logPos}, Event: e}
+    }
+
+    tests := []struct {
+        name string
+        ev   *replication.BinlogEvent
+        want []*BinlogEvent
+    }{
+        {
+            name: "rotate",
+            ev:   newEvent(replication.ROTATE_EVENT, 0, &replication.RotateEvent{Position: 4, NextLogName: []byte("binlog.000002")}),
+            want: []*BinlogEvent{{Header: BinlogEventHeader{EventType: byte(replication.ROTATE_EVENT)}, Event: &RotateEvent{Position: 4, File: "binlog.000002"}}},
+        },
+        {
+            name: "update",
+            ev: newEvent(replication.UPDATE_ROWS_EVENTv2, 200, &replication.RowsEvent{
+                Table:         tableMap,
+                ColumnBitmap1: []byte{0x03},
+                ColumnBitmap2: []byte{0x02},
+                Rows:          [][]any{{int32(1), "a"}, {nil, "b"}},
+            }),
+            want: []*BinlogEvent{{Header: BinlogEventHeader{EventType: byte(replication.UPDATE_ROWS_EVENTv2), LogPos: 200}, Event: &RowsEvent{
+                Table:         &TableMapEvent{TableID: 1, Schema: "game", Table: "player", ColumnTypes: tableMap.ColumnType, ColumnMeta: tableMap.ColumnMeta},
+                Before:        [][]any{{int64(1), []byte("a")}},
+                After:         [][]any{{nil, []byte("b")}},
+                BeforePresent: []bool{true, true},
+                AfterPresent:  []bool{false, true},
+            }}},
+        },
+        {
+            // 压缩事务展开为各事件，位置均为压缩事务的结束位置。
+            name: "payload",
+            ev: newEvent(replication.TRANSACTION_PAYLOAD_EVENT, 500, &replication.TransactionPayloadEvent{Events: []*replication.BinlogEvent{
+                newEvent(replication.QUERY_EVENT, 0, &replication.QueryEvent{Schema: []byte("game"), Query: []byte("BEGIN")}),
+                newEvent(replication.XID_EVENT, 0, &replication.XIDEvent{XID: 7}),
+            }}),
+            want: []*BinlogEvent{
+                {Header: BinlogEventHeader{EventType: byte(replication.QUERY_EVENT), LogPos: 500}, Event: &QueryEvent{Schema: "game", Query: "BEGIN"}},
+                {Header: BinlogEventHeader{EventType: byte(replication.XID_EVENT), LogPos: 500}, Event: &XIDEvent{XID: 7}},
+            },
+        },
+        {
+            name: "other",
+            ev:   newEvent(replication.FORMAT_DESCRIPTION_EVENT, 120, &replication.FormatDescriptionEvent{}),
+            want: []*BinlogEvent{{Header: BinlogEventHeader{EventType: byte(replication.FORMAT_DESCRIPTION_EVENT), LogPos: 120}}},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := getBinlogEvents(tt.ev, tt.ev.Header.LogPos)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("getBinlogEvents() = %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+
+    partial := newEvent(replication.PARTIAL_UPDATE_ROWS_EVENT, 300, &replication.RowsEvent{Table: tableMap})
+    if _, err := getBinlogEvents(partial, 300); err == nil {
+        t.Error("JSON 部分更新事件应返回错误")
+    }
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+    "bytes"
+    "database/sql"
+    "encoding/json"
     "fmt"
     "sort"
+    "strconv"
     "strings"
-    "sync/atomic"
+    "time"
 
     "github.com/asaskevich/govalidator"
     "github.com/camry/g/glog"
@@ -14,48 +18,66 @@ import (
 )
 
 type Converter struct {
-    serverDbConfig       *DbConfig
-    serverDb             *gorm.DB
-    serverTable          *Table
-    ignoreTable          *IgnoreTable
-    serverTableColumns   []string
-    serverTableColumnMap map[string]*MySQL2SQLiteColumn
+    serverDbConfig      *DbConfig
+    serverDb            *gorm.DB
+    serverTable         *Table
+    sqliteTableName     string
+    ignoreTable         *IgnoreTable
+    writer              Writer
+    serverTableColumns  []*MySQL2SQLiteColumn
+    serverInsertColumns []*MySQL2SQLiteColumn
+    serverTableKeys     []string
+    incrementalTable    *IncrementalTable
+    watermark           string
+    syncing             bool
+    filterTable         *FilterTable
+    plan                *TablePlan
 }
 
 type MySQL2SQLiteColumn struct {
-    DataType       string
-    SQLiteDataType string
+    ColumnName       string
+    SQLiteColumnName string
+    DataType         string
+    SQLiteDataType   string
+    NumericScale     int64
+    GeometryFormat   string
+    Mask             *MaskRule
+    Generated        bool
 }
 
 // NewConverter 新建转换器。
-func NewConverter(serverDbConfig *DbConfig, serverDb *gorm.DB, serverTable *Table, ignoreTable *IgnoreTable) *Converter {
+func NewConverter(serverDbConfig *DbConfig, serverDb *gorm.DB, serverTable *Table, ignoreTable *IgnoreTable, writer Writer) *Converter {
     return &Converter{
-        serverDbConfig:       serverDbConfig,
-        serverDb:             serverDb,
-        serverTable:          serverTable,
-        ignoreTable:          ignoreTable,
-        serverTableColumnMap: make(map[string]*MySQL2SQLiteColumn),
+        serverDbConfig:  serverDbConfig,
+        serverDb:        serverDb,
+        serverTable:     serverTable,
+        sqliteTableName: renameTable(serverTable.TableName),
+        ignoreTable:     ignoreTable,
+        writer:          writer,
+        filterTable:     filterMap[serverTable.TableName],
     }
 }
 
 // Start 启动。
 func (c *Converter) Start() {
     defer wg.Done()
-    ch <- true
 
     switch c.serverTable.TableType {
     case "BASE TABLE":
-        c.create()
-        c.insert()
+        if c.create() && !noData {
+            c.insert()
+        }
     case "VIEW":
-        // glog.Warnf("表 `%s` 不支持 VIEW 转换。", c.serverTable.TableName)
+        if !noCreate {
+            c.createView()
+        }
     }
 
     <-ch
 }
 
 // create SQLite CREATE TABLE 语句。
-func (c *Converter) create() {
+func (c *Converter) create() bool {
     var (
         serverColumnData     []Column
         serverStatisticsData []Statistic
@@ -74,31 +96,68 @@ func (c *Converter) create() {
             c.serverDbConfig.Database, c.serverTable.TableName,
         )
 
-        var createTableSql, createTableColumnSql, createUniqueIndexSql []string
+        var createTableSql, createTableColumnSql, createUniqueIndexSql, createIndexSql, createSequenceSql []string
 
-        createTableSql = append(createTableSql, fmt.Sprintf("DROP TABLE IF EXISTS `%s`;", c.serverTable.TableName))
-        createTableSql = append(createTableSql, fmt.Sprintf("CREATE TABLE `%s` (", c.serverTable.TableName))
+        createTableSql = append(createTableSql, fmt.Sprintf("DROP TABLE IF EXISTS `%s`;", c.sqliteTableName))
 
         // COLUMNS ...
+        sqliteColumnNames := make(map[string]string, len(serverColumnData))
         for _, serverColumn := range serverColumnData {
-            if gutil.InArray(serverColumn.ColumnName, c.ignoreTable.Columns) {
+            if c.isIgnoreColumn(serverColumn.ColumnName) {
+                c.planColumn(serverColumn, nil)
                 continue
             }
 
             dataType := strings.ToUpper(serverColumn.DataType)
             sqliteDataType := c.getDataType(dataType)
+            var geometryFormat string
+            if dataType == "DECIMAL" {
+                sqliteDataType = c.getDecimalDataType(serverColumn)
+            } else if isGeometryType(dataType) {
+                geometryFormat = c.getGeometryFormat(serverColumn)
+                sqliteDataType = "TEXT"
+                if geometryFormat == GeometryWKB {
+                    sqliteDataType = "BLOB"
+                }
+            }
 
-            createSql := fmt.Sprintf("  `%s` %s%s",
-                serverColumn.ColumnName,
+            columnName := c.renameColumn(serverColumn.ColumnName)
+            generatedSql := c.getGenerated(serverColumn, serverStatisticsData)
+            createSql := fmt.Sprintf("  `%s` %s%s%s%s",
+                columnName,
                 sqliteDataType,
                 c.getNotNull(serverColumn.IsNullable),
+                c.getDefault(serverColumn, sqliteDataType),
+                c.getCheck(serverColumn),
             )
+            if generatedSql != "" {
+                createSql = fmt.Sprintf("  `%s` %s%s%s",
+                    columnName,
+                    sqliteDataType,
+                    c.getNotNull(serverColumn.IsNullable),
+                    generatedSql,
+                )
+            }
             createTableColumnSql = append(createTableColumnSql, createSql)
 
-            c.serverTableColumns = append(c.serverTableColumns, serverColumn.ColumnName)
-            c.serverTableColumnMap[serverColumn.ColumnName] = &MySQL2SQLiteColumn{
-                DataType:       dataType,
-                SQLiteDataType: sqliteDataType,
+            column := &MySQL2SQLiteColumn{
+                ColumnName:       serverColumn.ColumnName,
+                SQLiteColumnName: columnName,
+                DataType:         dataType,
+                SQLiteDataType:   sqliteDataType,
+                NumericScale:     serverColumn.NumericScale.Int64,
+                GeometryFormat:   geometryFormat,
+                Mask:             c.getMaskRule(serverColumn),
+                Generated:        generatedSql != "",
+            }
+            if other, ok := sqliteColumnNames[strings.ToLower(columnName)]; ok {
+                glog.Fatalf("表 `%s` 字段 `%s`、`%s` 重命名后同为 `%s`。", c.serverTable.TableName, other, serverColumn.ColumnName, columnName)
+            }
+            sqliteColumnNames[strings.ToLower(columnName)] = serverColumn.ColumnName
+            c.serverTableColumns = append(c.serverTableColumns, column)
+            c.planColumn(serverColumn, column)
+            if !column.Generated {
+                c.serverInsertColumns = append(c.serverInsertColumns, column)
             }
         }
 
@@ -121,99 +180,534 @@ func (c *Converter) create() {
                 }
             }
 
+            var serverUniqueKeys []string
             for _, serverIndexName := range serverStatisticIndexNameArray {
                 if 1 != serverStatisticsDataMap[serverIndexName][1].NonUnique {
                     if serverIndexName == "PRIMARY" {
-                        createTableColumnSql = append(createTableColumnSql, fmt.Sprintf("  %s", c.getPrimaryKey(serverStatisticsDataMap[serverIndexName])))
+                        primaryKeySql := c.getPrimaryKey(serverStatisticsDataMap[serverIndexName])
+                        c.serverTableKeys = c.getIndexColumns(serverStatisticsDataMap[serverIndexName])
+                        c.checkRowidMask()
+                        if i := c.getAutoIncrementColumn(serverColumnData); i >= 0 {
+                            // 单字段自增主键: rowid 别名，并从 AUTO_INCREMENT 续接自增序列。
+                            createTableColumnSql[i] = fmt.Sprintf("  `%s` INTEGER PRIMARY KEY AUTOINCREMENT", c.serverTableColumns[i].SQLiteColumnName)
+                            createSequenceSql = c.createSequence()
+                            c.planIndex(serverIndexName, serverStatisticsDataMap[serverIndexName], "INTEGER PRIMARY KEY AUTOINCREMENT")
+                        } else {
+                            createTableColumnSql = append(createTableColumnSql, fmt.Sprintf("  %s", primaryKeySql))
+                            c.planIndex(serverIndexName, serverStatisticsDataMap[serverIndexName], "PRIMARY KEY")
+                        }
                     } else {
-                        createUniqueIndexSql = append(createUniqueIndexSql, c.createUniqueKey(serverIndexName, serverStatisticsDataMap[serverIndexName]))
+                        if uniqueKeySql := c.createUniqueKey(serverIndexName, serverStatisticsDataMap[serverIndexName]); uniqueKeySql != "" {
+                            createUniqueIndexSql = append(createUniqueIndexSql, uniqueKeySql)
+                            c.planIndex(serverIndexName, serverStatisticsDataMap[serverIndexName], "UNIQUE INDEX")
+                            if serverUniqueKeys == nil && !c.isNullableIndex(serverStatisticsDataMap[serverIndexName]) {
+                                serverUniqueKeys = c.getIndexColumns(serverStatisticsDataMap[serverIndexName])
+                            }
+                        } else {
+                            c.planIndex(serverIndexName, serverStatisticsDataMap[serverIndexName], "不转换")
+                        }
+                    }
+                } else if !noIndex {
+                    if indexSql := c.createIndex(serverIndexName, serverStatisticsDataMap[serverIndexName]); indexSql != "" {
+                        createIndexSql = append(createIndexSql, indexSql)
+                        c.planIndex(serverIndexName, serverStatisticsDataMap[serverIndexName], "INDEX")
+                    } else {
+                        c.planIndex(serverIndexName, serverStatisticsDataMap[serverIndexName], "不转换")
                     }
+                } else {
+                    c.planIndex(serverIndexName, serverStatisticsDataMap[serverIndexName], "不转换 (--no-index)")
                 }
             }
+            if c.serverTableKeys == nil {
+                c.serverTableKeys = serverUniqueKeys
+            }
         }
 
-        if len(createTableColumnSql) > 0 {
-            createTableSql = append(createTableSql, strings.Join(createTableColumnSql, ",\n"))
+        // FOREIGN KEY ...
+        createTableColumnSql = append(createTableColumnSql, c.getForeignKeys()...)
+
+        // 增量同步: 已同步过的表不重建，只写入变更数据。
+        if c.initIncremental() {
+            return true
         }
-        createTableSql = append(createTableSql, ");")
-        if len(createUniqueIndexSql) > 0 {
-            createTableSql = append(createTableSql, fmt.Sprintf("%s", strings.Join(createUniqueIndexSql, "\n")))
+
+        // 只导出数据: 写入已有的表结构。
+        if noCreate {
+            return true
         }
 
-        insertSql := c.insert()
+        createTableSql = append(createTableSql, fmt.Sprintf("CREATE TABLE `%s` (\n%s\n);",
+            c.sqliteTableName,
+            strings.Join(createTableColumnSql, ",\n"),
+        ))
+        createTableSql = append(createTableSql, createUniqueIndexSql...)
+        createTableSql = append(createTableSql, createIndexSql...)
+        createTableSql = append(createTableSql, createSequenceSql...)
 
-        if len(insertSql) > 0 {
-            createTableSql = append(createTableSql, fmt.Sprintf("%s", strings.Join(insertSql, "\n")))
+        if err := c.writer.Create(c.sqliteTableName, createTableSql); err != nil {
+            glog.Fatal(err)
         }
+        return true
+    }
+    return false
+}
+
+// initIncremental 初始化增量同步，返回是否按水位增量同步已存在的表。
+func (c *Converter) initIncremental() bool {
+    incrementalTable, ok := incrementalMap[c.serverTable.TableName]
+    if !incremental || !ok {
+        return false
+    }
+
+    var exists bool
+    for _, col := range c.serverInsertColumns {
+        if col.ColumnName == incrementalTable.Column {
+            exists = true
+        }
+    }
+    if !exists {
+        c.warnf("表 `%s` 增量同步水位字段 `%s` 不存在，全量导出。", c.serverTable.TableName, incrementalTable.Column)
+        return false
+    }
+    if len(c.serverTableKeys) == 0 {
+        c.warnf("表 `%s` 没有主键或非空唯一索引，无法增量同步，全量导出。", c.serverTable.TableName)
+        return false
+    }
+
+    c.incrementalTable = incrementalTable
+    c.watermark, c.syncing = watermarks[c.sqliteTableName]
+    return c.syncing
+}
+
+// createView SQLite CREATE VIEW 语句。
+func (c *Converter) createView() {
+    var serverView View
+
+    serverViewResult := c.serverDb.Table("VIEWS").Limit(1).Find(
+        &serverView,
+        "`TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?",
+        c.serverDbConfig.Database, c.serverTable.TableName,
+    )
+    if serverViewResult.RowsAffected <= 0 || serverView.ViewDefinition == "" {
+        c.addFailedView("无法读取视图定义，请检查 SHOW VIEW 权限")
+        return
+    }
+
+    viewDefinition, err := translateExpr(serverView.ViewDefinition, c.serverDbConfig.Database, "")
+    if err != nil {
+        c.addFailedView(err.Error())
+        return
+    }
+
+    // 转换后的视图定义先经 SQLite 校验，避免写入时失败。
+    createViewSql := fmt.Sprintf("CREATE VIEW `%s` AS %s;", c.sqliteTableName, viewDefinition)
+    if err = validateSql(createViewSql); err != nil {
+        c.addFailedView(err.Error())
+        return
+    }
+
+    if err = c.writer.Create(c.sqliteTableName, []string{
+        fmt.Sprintf("DROP VIEW IF EXISTS `%s`;", c.sqliteTableName),
+        createViewSql,
+    }); err != nil {
+        c.addFailedView(err.Error())
+    }
+}
+
+// addFailedView 记录无法转换的视图。
+func (c *Converter) addFailedView(reason string) {
+    lock.Lock()
+    defer lock.Unlock()
 
-        lock.Lock()
-        sqlTableNames = append(sqlTableNames, c.serverTable.TableName)
-        sqlTableMap[c.serverTable.TableName] = strings.Join(createTableSql, "\n")
-        lock.Unlock()
+    failedViews = append(failedViews, fmt.Sprintf("`%s`: %s", c.serverTable.TableName, reason))
+    if c.plan != nil {
+        c.plan.Notes = append(c.plan.Notes, fmt.Sprintf("视图无法转换: %s", reason))
     }
 }
 
 // insert SQLite INSERT INTO 语句。
-func (c *Converter) insert() []string {
+func (c *Converter) insert() {
+    if subsetRows != nil {
+        c.insertSubset()
+        return
+    }
+
+    // 读取前记录水位，读取期间的变更留给下次同步。
+    var watermark string
+    if c.incrementalTable != nil {
+        watermark = c.getWatermark()
+    }
+
+    if c.syncing {
+        c.insertKeyset(c.getWatermarkScope(watermark), c.upsertRows)
+    } else if len(c.serverTableKeys) == 0 || c.isOrdered() {
+        c.insertScan()
+    } else if bounds := c.getChunkBounds(); len(bounds) > 0 {
+        c.insertChunks(bounds)
+    } else {
+        c.insertKeyset(nil, c.insertRows)
+    }
+
+    if watermark != "" {
+        if err := c.writer.SetWatermark(c.sqliteTableName, c.renameColumn(c.incrementalTable.Column), watermark); err != nil {
+            glog.Fatal(err)
+        }
+    }
+}
+
+// getWatermark 增量同步水位字段当前最大值，空表返回空字符串。
+func (c *Converter) getWatermark() string {
+    var maxValue any
+
+    serverDb, release := c.acquireDb()
+    defer release()
+
+    err := serverDb.Table(fmt.Sprintf("`%s`.`%s`", c.serverDbConfig.Database, c.serverTable.TableName)).
+        Scopes(c.filterRows).
+        Select(fmt.Sprintf("MAX(`%s`)", c.incrementalTable.Column)).
+        Row().Scan(&maxValue)
+    if err != nil {
+        glog.Fatal(err)
+    }
+
+    switch v := maxValue.(type) {
+    case nil:
+        return ""
+    case time.Time:
+        return v.Format("2006-01-02 15:04:05.999999")
+    case []byte:
+        return string(v)
+    }
+    return govalidator.ToString(maxValue)
+}
+
+// getWatermarkScope 增量同步范围条件: last <= column <= watermark。
+// 水位相等的行可能在上次同步后更新，重复读取由 ON CONFLICT DO UPDATE 保证幂等。
+func (c *Converter) getWatermarkScope(watermark string) func(db *gorm.DB) *gorm.DB {
+    return func(db *gorm.DB) *gorm.DB {
+        db = db.Where(fmt.Sprintf("`%s` >= ?", c.incrementalTable.Column), c.watermark)
+        if watermark != "" {
+            db = db.Where(fmt.Sprintf("`%s` <= ?", c.incrementalTable.Column), watermark)
+        }
+        return db
+    }
+}
+
+// insertKeyset 按主键（或非空唯一索引）分页: WHERE key > last ORDER BY key LIMIT n，scope 附加范围条件。
+func (c *Converter) insertKeyset(scope func(db *gorm.DB) *gorm.DB, fn func(rows []map[string]any)) {
     var (
-        insertSql []string
-        offset    = 0
-        limit     = 2000
+        lastKeys []any
+        limit    = 2000
     )
 
+    serverDb, release := c.acquireDb()
+    defer release()
+
     for {
         var rows []map[string]any
-        result := c.serverDb.Table(fmt.Sprintf("`%s`.`%s`", c.serverDbConfig.Database, c.serverTable.TableName)).Offset(offset).Limit(limit).Find(&rows)
+        query := serverDb.Table(fmt.Sprintf("`%s`.`%s`", c.serverDbConfig.Database, c.serverTable.TableName)).Scopes(c.filterRows)
+        if scope != nil {
+            query = query.Scopes(scope)
+        }
+        if lastKeys != nil {
+            where, args := c.getKeysetWhere(lastKeys)
+            query = query.Where(where, args...)
+        }
+        for _, key := range c.serverTableKeys {
+            query = query.Order(fmt.Sprintf("`%s` ASC", key))
+        }
+        result := query.Limit(limit).Find(&rows)
+        if result.Error != nil {
+            glog.Fatal(result.Error)
+        }
         if result.RowsAffected <= 0 {
             break
         }
 
-        var ks, kv []string
-        for _, columnName := range c.serverTableColumns {
-            ks = append(ks, fmt.Sprintf("`%s`", columnName))
+        fn(rows)
+
+        lastKeys = make([]any, 0, len(c.serverTableKeys))
+        for _, key := range c.serverTableKeys {
+            lastKeys = append(lastKeys, rows[len(rows)-1][key])
+        }
+
+        if len(rows) < limit {
+            break
         }
-        for _, row := range rows {
-            var vs []string
-            for _, columnName := range c.serverTableColumns {
-                if col, ok := c.serverTableColumnMap[columnName]; ok {
-                    if columnValue, ok1 := row[columnName]; ok1 {
-                        if columnValue == nil {
-                            vs = append(vs, "NULL")
-                        } else {
-                            switch col.SQLiteDataType {
-                            case "INTEGER", "REAL":
-                                vs = append(vs, govalidator.ToString(columnValue))
-                            case "TEXT":
-                                switch col.DataType {
-                                case "DATE":
-                                    vs = append(vs, fmt.Sprintf("'%s'", carbon.Parse(govalidator.ToString(columnValue)).ToDateString()))
-                                case "TIME":
-                                    vs = append(vs, fmt.Sprintf("'%s'", carbon.Parse(govalidator.ToString(columnValue)).ToTimeString()))
-                                case "YEAR":
-                                    vs = append(vs, fmt.Sprintf("'%d'", carbon.Parse(govalidator.ToString(columnValue)).Year()))
-                                case "DATETIME", "TIMESTAMP":
-                                    vs = append(vs, fmt.Sprintf("'%s'", carbon.Parse(govalidator.ToString(columnValue)).ToDateTimeString()))
-                                default:
-                                    vs = append(vs, fmt.Sprintf("'%s'", strings.ReplaceAll(govalidator.ToString(columnValue), "'", "''")))
-                                }
-                            case "BLOB":
-                                vs = append(vs, fmt.Sprintf("'%s'", govalidator.ToString(columnValue)))
-                            }
-                        }
-                    }
-                }
+    }
+}
+
+// getChunkBounds 大表按单字段整型主键范围分块的分界值，不分块返回 nil。
+func (c *Converter) getChunkBounds() []int64 {
+    if chunkSize <= 0 || chunkWorkers <= 1 || len(c.serverTableKeys) != 1 ||
+        !c.serverTable.TableRows.Valid || c.serverTable.TableRows.Int64 <= chunkSize {
+        return nil
+    }
+    for _, col := range c.serverTableColumns {
+        if col.ColumnName == c.serverTableKeys[0] && (col.SQLiteDataType != "INTEGER" || col.DataType == "DECIMAL") {
+            return nil
+        }
+    }
+
+    var keyRange struct {
+        MinKey sql.NullInt64
+        MaxKey sql.NullInt64
+    }
+
+    // 与分块读取使用同一快照连接，分块边界与读取的数据一致。
+    serverDb, release := c.acquireDb()
+    defer release()
+
+    result := serverDb.Table(fmt.Sprintf("`%s`.`%s`", c.serverDbConfig.Database, c.serverTable.TableName)).
+        Scopes(c.filterRows).
+        Select(fmt.Sprintf("MIN(`%s`) AS min_key, MAX(`%s`) AS max_key", c.serverTableKeys[0], c.serverTableKeys[0])).
+        Take(&keyRange)
+    if result.Error != nil {
+        glog.Fatal(result.Error)
+    }
+    if !keyRange.MinKey.Valid || !keyRange.MaxKey.Valid {
+        return nil
+    }
+
+    var bounds []int64
+    chunks := (c.serverTable.TableRows.Int64 + chunkSize - 1) / chunkSize
+    step := (keyRange.MaxKey.Int64-keyRange.MinKey.Int64)/chunks + 1
+    for bound := keyRange.MinKey.Int64 + step; bound <= keyRange.MaxKey.Int64 && bound > keyRange.MinKey.Int64; bound += step {
+        bounds = append(bounds, bound)
+    }
+    return bounds
+}
+
+// insertChunks 按主键范围分块并发读取，按分块顺序输出。
+func (c *Converter) insertChunks(bounds []int64) {
+    var (
+        chunks  = make([]chan [][]any, len(bounds)+1)
+        workers = make(chan bool, chunkWorkers)
+    )
+    for i := range chunks {
+        chunks[i] = make(chan [][]any, 2)
+    }
+
+    // 按分块顺序占用并发槽位，保证当前输出的分块已在读取中。
+    go func() {
+        for i := range chunks {
+            workers <- true
+            go func(i int) {
+                defer func() {
+                    close(chunks[i])
+                    <-workers
+                }()
+                c.insertKeyset(c.getChunkScope(bounds, i), func(rows []map[string]any) {
+                    chunks[i] <- c.getValues(rows)
+                })
+            }(i)
+        }
+    }()
+
+    for _, chunk := range chunks {
+        for values := range chunk {
+            if err := c.writer.Insert(c.sqliteTableName, c.serverInsertColumns, values); err != nil {
+                glog.Fatal(err)
             }
-            kv = append(kv, fmt.Sprintf("(%s)", strings.Join(vs, ",")))
         }
-        insertSql = append(insertSql, fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;",
-            c.serverTable.TableName,
-            strings.Join(ks, ","),
-            strings.Join(kv, ","),
-        ))
+    }
+}
+
+// getChunkScope 第 i 个分块的主键范围条件: bounds[i-1] <= key < bounds[i]，首尾分块不限下界、上界。
+func (c *Converter) getChunkScope(bounds []int64, i int) func(db *gorm.DB) *gorm.DB {
+    return func(db *gorm.DB) *gorm.DB {
+        if i > 0 {
+            db = db.Where(fmt.Sprintf("`%s` >= ?", c.serverTableKeys[0]), bounds[i-1])
+        }
+        if i < len(bounds) {
+            db = db.Where(fmt.Sprintf("`%s` < ?", c.serverTableKeys[0]), bounds[i])
+        }
+        return db
+    }
+}
+
+// insertScan 无主键和唯一索引的表，或配置了 order_by、limit 的表，单次有序扫描。
+func (c *Converter) insertScan() {
+    var (
+        batch []map[string]any
+        limit = 2000
+    )
+
+    serverDb, release := c.acquireDb()
+    defer release()
+
+    query := serverDb.Table(fmt.Sprintf("`%s`.`%s`", c.serverDbConfig.Database, c.serverTable.TableName)).Scopes(c.filterRows)
+    if c.filterTable != nil && c.filterTable.OrderBy != "" {
+        query = query.Order(c.filterTable.OrderBy)
+    } else if len(c.serverTableKeys) > 0 {
+        for _, key := range c.serverTableKeys {
+            query = query.Order(fmt.Sprintf("`%s` ASC", key))
+        }
+    } else {
+        for _, col := range c.serverTableColumns {
+            query = query.Order(fmt.Sprintf("`%s` ASC", col.ColumnName))
+        }
+    }
+    if c.filterTable != nil && c.filterTable.Limit > 0 {
+        query = query.Limit(int(c.filterTable.Limit))
+    }
+    rows, err := query.Rows()
+    if err != nil {
+        glog.Fatal(err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        row := make(map[string]any)
+        if err = c.serverDb.ScanRows(rows, &row); err != nil {
+            glog.Fatal(err)
+        }
+        batch = append(batch, row)
 
-        offset += limit
+        if len(batch) >= limit {
+            c.insertRows(batch)
+            batch = nil
+        }
+    }
+    if err = rows.Err(); err != nil {
+        glog.Fatal(err)
+    }
+    if len(batch) > 0 {
+        c.insertRows(batch)
+    }
+}
+
+// insertSubset 输出数据子集中的行。
+func (c *Converter) insertSubset() {
+    limit := 2000
+    rows := subsetRows[c.serverTable.TableName]
+    for start := 0; start < len(rows); start += limit {
+        end := start + limit
+        if end > len(rows) {
+            end = len(rows)
+        }
+        c.insertRows(rows[start:end])
+    }
+}
+
+// isOrdered 是否配置了 order_by 或 limit，需要单次有序扫描。(增量同步时不适用)
+func (c *Converter) isOrdered() bool {
+    return c.filterTable != nil && (c.filterTable.OrderBy != "" || c.filterTable.Limit > 0)
+}
+
+// filterRows 配置文件 filters 指定的 WHERE 条件。
+func (c *Converter) filterRows(db *gorm.DB) *gorm.DB {
+    if c.filterTable != nil && c.filterTable.Where != "" {
+        db = db.Where(fmt.Sprintf("(%s)", c.filterTable.Where))
+    }
+    return db
+}
+
+// acquireDb 读取表数据的连接，--single-transaction 时使用一致性快照连接。
+func (c *Converter) acquireDb() (*gorm.DB, func()) {
+    if snapshot == nil {
+        return c.serverDb, func() {}
+    }
+    session := snapshot.Acquire()
+    return session, func() {
+        snapshot.Release(session)
+    }
+}
+
+// insertRows 转换并输出一批数据行。
+func (c *Converter) insertRows(rows []map[string]any) {
+    if err := c.writer.Insert(c.sqliteTableName, c.serverInsertColumns, c.getValues(rows)); err != nil {
+        glog.Fatal(err)
+    }
+}
+
+// upsertRows 转换并按主键（或非空唯一索引）冲突时更新输出一批数据行。
+func (c *Converter) upsertRows(rows []map[string]any) {
+    if err := c.writer.Upsert(c.sqliteTableName, c.serverInsertColumns, c.getSQLiteKeys(), c.getValues(rows)); err != nil {
+        glog.Fatal(err)
+    }
+}
+
+// getValues 转换一批数据行。
+func (c *Converter) getValues(rows []map[string]any) [][]any {
+    var values [][]any
+    for _, row := range rows {
+        var vs []any
+        for _, col := range c.serverInsertColumns {
+            vs = append(vs, maskValue(col, c.getValue(col, row[col.ColumnName])))
+        }
+        values = append(values, vs)
+    }
+    return values
+}
+
+// getKeysetWhere 分页条件: (k1 > ?) OR (k1 = ? AND k2 > ?) OR ...
+func (c *Converter) getKeysetWhere(lastKeys []any) (string, []any) {
+    var (
+        ors  []string
+        args []any
+    )
+    for i, key := range c.serverTableKeys {
+        var ands []string
+        for j := 0; j < i; j++ {
+            ands = append(ands, fmt.Sprintf("`%s` = ?", c.serverTableKeys[j]))
+            args = append(args, lastKeys[j])
+        }
+        ands = append(ands, fmt.Sprintf("`%s` > ?", key))
+        args = append(args, lastKeys[i])
+        ors = append(ors, fmt.Sprintf("(%s)", strings.Join(ands, " AND ")))
+    }
+    return strings.Join(ors, " OR "), args
+}
+
+// getValue SQLite 字段值。
+func (c *Converter) getValue(col *MySQL2SQLiteColumn, columnValue any) any {
+    if columnValue == nil {
+        return nil
+    }
+    if col.GeometryFormat != "" {
+        value, err := convertGeometry([]byte(govalidator.ToString(columnValue)), col.GeometryFormat)
+        if err != nil {
+            glog.Fatalf("表 `%s` 字段 `%s` 空间数据无法转换为 %s: %s", c.serverTable.TableName, col.ColumnName, col.GeometryFormat, err)
+        }
+        return value
+    }
+    switch col.SQLiteDataType {
+    case "INTEGER", "REAL":
+        if col.SQLiteDataType == "INTEGER" && col.DataType == "DECIMAL" {
+            scaled, err := scaleDecimal(govalidator.ToString(columnValue), col.NumericScale)
+            if err != nil {
+                glog.Fatalf("表 `%s` 字段 `%s` 值 %v 无法转换为 INTEGER: %s", c.serverTable.TableName, col.ColumnName, columnValue, err)
+            }
+            return scaled
+        }
+        return columnValue
+    case "TEXT":
+        switch col.DataType {
+        case "DATE":
+            return carbon.Parse(govalidator.ToString(columnValue)).ToDateString()
+        case "TIME":
+            return carbon.Parse(govalidator.ToString(columnValue)).ToTimeString()
+        case "YEAR":
+            return fmt.Sprintf("%d", carbon.Parse(govalidator.ToString(columnValue)).Year())
+        case "DATETIME", "TIMESTAMP":
+            return carbon.Parse(govalidator.ToString(columnValue)).ToDateTimeString()
+        case "JSON":
+            var buf bytes.Buffer
+            if err := json.Compact(&buf, []byte(govalidator.ToString(columnValue))); err == nil {
+                return buf.String()
+            }
+        }
+    case "BLOB":
+        switch v := columnValue.(type) {
+        case []byte:
+            return v
+        case string:
+            return []byte(v)
+        }
+        return []byte(govalidator.ToString(columnValue))
     }
-    return insertSql
+    return govalidator.ToString(columnValue)
 }
 
 // getDataType SQLite 数据类型。
@@ -223,14 +717,93 @@ func (c *Converter) getDataType(dataType string) string {
         return "INTEGER"
     case "FLOAT", "DOUBLE", "DECIMAL":
         return "REAL"
-    case "DATE", "TIME", "YEAR", "DATETIME", "TIMESTAMP", "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT":
+    case "DATE", "TIME", "YEAR", "DATETIME", "TIMESTAMP", "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT", "ENUM", "SET":
         return "TEXT"
-    case "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB":
+    case "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "BIT":
         return "BLOB"
     }
     return "TEXT"
 }
 
+// getDecimalDataType DECIMAL 字段 SQLite 数据类型，配置文件按表字段指定，否则使用 --decimal。
+func (c *Converter) getDecimalDataType(serverColumn Column) string {
+    mode := decimalMode
+    for _, decimalTable := range decimalTables {
+        if decimalTable.Table == c.serverTable.TableName && (len(decimalTable.Columns) == 0 || gutil.InArray(serverColumn.ColumnName, decimalTable.Columns)) {
+            mode = decimalTable.Mode
+        }
+    }
+
+    switch mode {
+    case DecimalText:
+        return "TEXT"
+    case DecimalInteger:
+        if serverColumn.NumericPrecision.Int64 > 18 {
+            c.warnf("表 `%s` 字段 `%s` %s 按 INTEGER 存储可能溢出。", c.serverTable.TableName, serverColumn.ColumnName, serverColumn.ColumnType)
+        }
+        return "INTEGER"
+    }
+    return "REAL"
+}
+
+// getMaskRule 字段脱敏规则，未配置返回 nil。
+func (c *Converter) getMaskRule(serverColumn Column) *MaskRule {
+    var maskRule *MaskRule
+    for _, rule := range maskRules {
+        if rule.Table == c.serverTable.TableName && gutil.InArray(serverColumn.ColumnName, rule.Columns) {
+            maskRule = rule
+        }
+    }
+    return maskRule
+}
+
+// checkRowidMask 单字段 INTEGER 主键为 SQLite rowid 别名，只能写入整数，脱敏结果为文本或 NULL 的规则无法写入。
+func (c *Converter) checkRowidMask() {
+    if len(c.serverTableKeys) != 1 {
+        return
+    }
+    for _, col := range c.serverTableColumns {
+        if col.ColumnName != c.serverTableKeys[0] || col.SQLiteDataType != "INTEGER" || col.Mask == nil || isIntegerMask(col.Mask) {
+            continue
+        }
+        if c.plan == nil {
+            glog.Fatalf("表 `%s` 主键字段 `%s` 为 INTEGER rowid，脱敏规则 %s 的结果不是整数。", c.serverTable.TableName, col.ColumnName, col.Mask.Rule)
+        }
+        c.warnf("表 `%s` 主键字段 `%s` 为 INTEGER rowid，脱敏规则 %s 的结果不是整数，转换时将终止。", c.serverTable.TableName, col.ColumnName, col.Mask.Rule)
+    }
+}
+
+// getGeometryFormat 空间数据转换格式，配置文件按表字段指定，否则使用 --geometry。
+func (c *Converter) getGeometryFormat(serverColumn Column) string {
+    format := geometryFormat
+    for _, geometryTable := range geometryTables {
+        if geometryTable.Table == c.serverTable.TableName && (len(geometryTable.Columns) == 0 || gutil.InArray(serverColumn.ColumnName, geometryTable.Columns)) {
+            format = geometryTable.Format
+        }
+    }
+    return format
+}
+
+// scaleDecimal 十进制字符串按精度放大为整数: scaleDecimal("12.34", 3) = 12340。
+func scaleDecimal(s string, scale int64) (int64, error) {
+    s = strings.TrimSpace(s)
+    sign := ""
+    if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+        sign, s = s[:1], s[1:]
+    }
+
+    integer, fraction, _ := strings.Cut(s, ".")
+    if int64(len(fraction)) > scale {
+        if strings.Trim(fraction[scale:], "0") != "" {
+            return 0, fmt.Errorf("小数位超过 %d 位", scale)
+        }
+        fraction = fraction[:scale]
+    }
+    fraction += strings.Repeat("0", int(scale)-len(fraction))
+
+    return strconv.ParseInt(sign+integer+fraction, 10, 64)
+}
+
 // getNotNull SQLite NOT NULL 语句。
 func (c *Converter) getNotNull(isNullAble string) string {
     if isNullAble == "NO" {
@@ -239,6 +812,163 @@ func (c *Converter) getNotNull(isNullAble string) string {
     return ""
 }
 
+// getDefault SQLite DEFAULT 语句。
+func (c *Converter) getDefault(serverColumn Column, sqliteDataType string) string {
+    if !serverColumn.ColumnDefault.Valid {
+        return ""
+    }
+    columnDefault := serverColumn.ColumnDefault.String
+
+    // 表达式默认值: CURRENT_TIMESTAMP、(expr)
+    if strings.Contains(strings.ToUpper(serverColumn.EXTRA), "DEFAULT_GENERATED") || strings.HasPrefix(strings.ToUpper(columnDefault), "CURRENT_TIMESTAMP") {
+        expr, err := translateExpr(strings.ReplaceAll(columnDefault, "\\'", "'"), c.serverDbConfig.Database, "")
+        if err != nil {
+            c.warnf("表 `%s` 字段 `%s` 默认值 %s 无法转换: %s", c.serverTable.TableName, serverColumn.ColumnName, columnDefault, err)
+            return ""
+        }
+        switch expr = strings.TrimSpace(expr); expr {
+        case "CURRENT_TIMESTAMP", "CURRENT_DATE", "CURRENT_TIME":
+            return fmt.Sprintf(" DEFAULT %s", expr)
+        }
+        return fmt.Sprintf(" DEFAULT %s", wrapParen(expr))
+    }
+
+    // 位字面量: b'101'，BIT 字段按 BLOB 存储时与数据一致输出 (M+7)/8 字节的 X'05'。
+    if strings.HasPrefix(columnDefault, "b'") && strings.HasSuffix(columnDefault, "'") {
+        if bit, err := strconv.ParseUint(columnDefault[2:len(columnDefault)-1], 2, 64); err == nil {
+            if sqliteDataType == "BLOB" {
+                width := (serverColumn.NumericPrecision.Int64 + 7) / 8
+                if width < 1 {
+                    width = 1
+                }
+                return fmt.Sprintf(" DEFAULT X'%0*X'", width*2, bit)
+            }
+            return fmt.Sprintf(" DEFAULT %d", bit)
+        }
+    }
+
+    switch sqliteDataType {
+    case "INTEGER", "REAL":
+        if govalidator.IsFloat(columnDefault) {
+            if sqliteDataType == "INTEGER" && strings.ToUpper(serverColumn.DataType) == "DECIMAL" {
+                if scaled, err := scaleDecimal(columnDefault, serverColumn.NumericScale.Int64); err == nil {
+                    return fmt.Sprintf(" DEFAULT %d", scaled)
+                }
+            }
+            return fmt.Sprintf(" DEFAULT %s", columnDefault)
+        }
+    }
+    return fmt.Sprintf(" DEFAULT %s", quoteString(columnDefault))
+}
+
+// getGenerated SQLite GENERATED ALWAYS AS 语句，表达式无法转换时按普通字段导出。
+func (c *Converter) getGenerated(serverColumn Column, serverStatisticsData []Statistic) string {
+    extra := strings.ToUpper(serverColumn.EXTRA)
+    if !strings.Contains(extra, "VIRTUAL GENERATED") && !strings.Contains(extra, "STORED GENERATED") {
+        return ""
+    }
+
+    for _, serverStatistic := range serverStatisticsData {
+        if serverStatistic.IndexName == "PRIMARY" && serverStatistic.ColumnName == serverColumn.ColumnName {
+            c.warnf("表 `%s` 生成列 `%s` 为主键字段，按普通字段导出。", c.serverTable.TableName, serverColumn.ColumnName)
+            return ""
+        }
+    }
+
+    // JSON 路径生成列仅在 --json-generated 时转换。
+    generationExpression := strings.ReplaceAll(serverColumn.GenerationExpression, "\\'", "'")
+    if !jsonGenerated && isJSONPathExpr(generationExpression) {
+        return ""
+    }
+
+    expr, err := translateExpr(generationExpression, c.serverDbConfig.Database, c.serverTable.TableName)
+    if err != nil {
+        c.warnf("表 `%s` 生成列 `%s` 表达式 %s 无法转换，按普通字段导出: %s", c.serverTable.TableName, serverColumn.ColumnName, serverColumn.GenerationExpression, err)
+        return ""
+    }
+
+    if tokens, err := tokenize(generationExpression); err == nil {
+        for _, tk := range tokens {
+            if tk.kind == tokenQuoted && c.isIgnoreColumn(tk.value) {
+                c.warnf("表 `%s` 生成列 `%s` 引用已忽略字段 `%s`，按普通字段导出。", c.serverTable.TableName, serverColumn.ColumnName, tk.value)
+                return ""
+            }
+        }
+    }
+
+    storage := "VIRTUAL"
+    if strings.Contains(extra, "STORED GENERATED") {
+        storage = "STORED"
+    }
+    return fmt.Sprintf(" GENERATED ALWAYS AS %s %s", wrapParen(strings.TrimSpace(expr)), storage)
+}
+
+// getCheck SQLite CHECK 语句。
+func (c *Converter) getCheck(serverColumn Column) string {
+    var members []string
+
+    dataType := strings.ToUpper(serverColumn.DataType)
+    if dataType == "JSON" && jsonCheck {
+        return fmt.Sprintf(" CHECK (json_valid(`%s`))", c.renameColumn(serverColumn.ColumnName))
+    }
+    if dataType != "ENUM" && dataType != "SET" {
+        return ""
+    }
+
+    // enum('a','b') / set('a','b')
+    tokens, err := tokenize(serverColumn.ColumnType)
+    if err != nil {
+        c.warnf("表 `%s` 字段 `%s` 类型 %s 无法解析: %s", c.serverTable.TableName, serverColumn.ColumnName, serverColumn.ColumnType, err)
+        return ""
+    }
+    for _, tk := range tokens {
+        if tk.kind == tokenString {
+            members = append(members, tk.value)
+        }
+    }
+    if len(members) == 0 {
+        return ""
+    }
+
+    columnName := c.renameColumn(serverColumn.ColumnName)
+    if dataType == "ENUM" {
+        // 非严格模式下非法 ENUM 值保存为 ''。
+        values := []string{"''"}
+        for _, member := range members {
+            if member != "" {
+                values = append(values, quoteString(member))
+            }
+        }
+        return fmt.Sprintf(" CHECK (`%s` IN (%s))", columnName, strings.Join(values, ","))
+    }
+
+    // SET: 逐个移除 ',member,' 后只剩逗号，即每个元素均为合法成员；非空值不能含空元素。
+    expr := fmt.Sprintf("',' || `%s` || ','", columnName)
+    for _, member := range members {
+        expr = fmt.Sprintf("replace(%s, %s, ',,')", expr, quoteString(","+member+","))
+    }
+    return fmt.Sprintf(" CHECK (`%s` = '' OR (replace(%s, ',', '') = '' AND instr(',' || `%s` || ',', ',,') = 0))", columnName, expr, columnName)
+}
+
+// renameColumn 当前表字段的 SQLite 字段名。
+func (c *Converter) renameColumn(columnName string) string {
+    return renameColumn(c.serverTable.TableName, columnName)
+}
+
+// getSQLiteKeys 主键（或非空唯一索引）的 SQLite 字段名。
+func (c *Converter) getSQLiteKeys() []string {
+    var keys []string
+    for _, key := range c.serverTableKeys {
+        keys = append(keys, c.renameColumn(key))
+    }
+    return keys
+}
+
+// isIgnoreColumn 当前表字段是否忽略。
+func (c *Converter) isIgnoreColumn(columnName string) bool {
+    return isIgnoreColumn(c.serverTable.TableName, columnName)
+}
+
 // getPrimaryKey SQLite PRIMARY KEY 语句。
 func (c *Converter) getPrimaryKey(statisticMap map[int]Statistic) string {
     var seqInIndexSort []int
@@ -251,23 +981,152 @@ func (c *Converter) getPrimaryKey(statisticMap map[int]Statistic) string {
     sort.Ints(seqInIndexSort)
 
     for _, seqInIndex := range seqInIndexSort {
-        if gutil.InArray(statisticMap[seqInIndex].ColumnName, c.ignoreTable.Columns) {
-            glog.Fatalf(`PRIMARY KEY Column %s is not ignore.`, statisticMap[seqInIndex].ColumnName)
+        if c.isIgnoreColumn(statisticMap[seqInIndex].ColumnName) {
+            if c.plan == nil {
+                glog.Fatalf(`PRIMARY KEY Column %s is not ignore.`, statisticMap[seqInIndex].ColumnName)
+            }
+            c.warnf("表 `%s` 主键字段 `%s` 不能忽略，转换时将终止。", c.serverTable.TableName, statisticMap[seqInIndex].ColumnName)
         }
-        columnNames = append(columnNames, fmt.Sprintf("`%s`", statisticMap[seqInIndex].ColumnName))
+        columnNames = append(columnNames, fmt.Sprintf("`%s`", c.renameColumn(statisticMap[seqInIndex].ColumnName)))
     }
 
     return fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(columnNames, ","))
 }
 
+// getAutoIncrementColumn 单字段整型自增主键在 serverTableColumns 中的下标，不存在返回 -1。
+func (c *Converter) getAutoIncrementColumn(serverColumnData []Column) int {
+    if len(c.serverTableKeys) != 1 {
+        return -1
+    }
+    for _, serverColumn := range serverColumnData {
+        if serverColumn.ColumnName != c.serverTableKeys[0] || !strings.Contains(strings.ToLower(serverColumn.EXTRA), "auto_increment") {
+            continue
+        }
+        for i, col := range c.serverTableColumns {
+            if col.ColumnName == serverColumn.ColumnName && col.SQLiteDataType == "INTEGER" {
+                return i
+            }
+        }
+    }
+    return -1
+}
+
+// createSequence SQLite sqlite_sequence 自增序列语句。
+func (c *Converter) createSequence() []string {
+    if !c.serverTable.AutoIncrement.Valid || c.serverTable.AutoIncrement.Int64 <= 1 {
+        return nil
+    }
+    return []string{
+        fmt.Sprintf("DELETE FROM sqlite_sequence WHERE `name` = %s;", quoteString(c.sqliteTableName)),
+        fmt.Sprintf("INSERT INTO sqlite_sequence (`name`, `seq`) VALUES (%s, %d);", quoteString(c.sqliteTableName), c.serverTable.AutoIncrement.Int64-1),
+    }
+}
+
+// getIndexColumns 按 SeqInIndex 排序的索引字段。
+func (c *Converter) getIndexColumns(statisticMap map[int]Statistic) []string {
+    var seqInIndexSort []int
+    var columnNames []string
+
+    for seqInIndex := range statisticMap {
+        seqInIndexSort = append(seqInIndexSort, seqInIndex)
+    }
+
+    sort.Ints(seqInIndexSort)
+
+    for _, seqInIndex := range seqInIndexSort {
+        columnNames = append(columnNames, statisticMap[seqInIndex].ColumnName)
+    }
+
+    return columnNames
+}
+
+// isNullableIndex 索引是否包含可为 NULL 的字段。
+func (c *Converter) isNullableIndex(statisticMap map[int]Statistic) bool {
+    for _, statistic := range statisticMap {
+        if statistic.NULLABLE == "YES" {
+            return true
+        }
+    }
+    return false
+}
+
+// getForeignKeys SQLite FOREIGN KEY 语句。
+func (c *Converter) getForeignKeys() []string {
+    var (
+        foreignKeys                  []string
+        serverReferentialConstraints []ReferentialConstraints
+        serverKeyColumnUsageData     []KeyColumnUsage
+    )
+
+    serverReferentialResult := c.serverDb.Table("REFERENTIAL_CONSTRAINTS").Order("`CONSTRAINT_NAME` ASC").Find(
+        &serverReferentialConstraints,
+        "`CONSTRAINT_SCHEMA` = ? AND `TABLE_NAME` = ?",
+        c.serverDbConfig.Database, c.serverTable.TableName,
+    )
+    if serverReferentialResult.RowsAffected <= 0 {
+        return nil
+    }
+
+    c.serverDb.Table("KEY_COLUMN_USAGE").Order("`CONSTRAINT_NAME` ASC, `ORDINAL_POSITION` ASC").Find(
+        &serverKeyColumnUsageData,
+        "`TABLE_SCHEMA` = ? AND `TABLE_NAME` = ? AND `REFERENCED_TABLE_NAME` IS NOT NULL",
+        c.serverDbConfig.Database, c.serverTable.TableName,
+    )
+
+    for _, serverReferential := range serverReferentialConstraints {
+        var columnNames, referencedColumnNames []string
+        isContinue := true
+
+        for _, serverKeyColumnUsage := range serverKeyColumnUsageData {
+            if serverKeyColumnUsage.ConstraintName != serverReferential.ConstraintName {
+                continue
+            }
+            if serverKeyColumnUsage.ReferencedTableSchema != c.serverDbConfig.Database {
+                c.warnf("表 `%s` 外键 `%s` 引用其他数据库 `%s`，跳过该外键。", c.serverTable.TableName, serverReferential.ConstraintName, serverKeyColumnUsage.ReferencedTableSchema)
+                isContinue = false
+                break
+            }
+            if c.isIgnoreColumn(serverKeyColumnUsage.ColumnName) {
+                c.warnf("表 `%s` 外键 `%s` 字段 `%s` 已忽略，跳过该外键。", c.serverTable.TableName, serverReferential.ConstraintName, serverKeyColumnUsage.ColumnName)
+                isContinue = false
+                break
+            }
+            if isIgnoreTable(serverKeyColumnUsage.ReferencedTableName) {
+                c.warnf("表 `%s` 外键 `%s` 引用表 `%s` 已忽略，跳过该外键。", c.serverTable.TableName, serverReferential.ConstraintName, serverKeyColumnUsage.ReferencedTableName)
+                isContinue = false
+                break
+            }
+            if isIgnoreColumn(serverKeyColumnUsage.ReferencedTableName, serverKeyColumnUsage.ReferencedColumnName) {
+                c.warnf("表 `%s` 外键 `%s` 引用字段 `%s`.`%s` 已忽略，跳过该外键。", c.serverTable.TableName, serverReferential.ConstraintName, serverKeyColumnUsage.ReferencedTableName, serverKeyColumnUsage.ReferencedColumnName)
+                isContinue = false
+                break
+            }
+
+            columnNames = append(columnNames, fmt.Sprintf("`%s`", c.renameColumn(serverKeyColumnUsage.ColumnName)))
+            referencedColumnNames = append(referencedColumnNames, fmt.Sprintf("`%s`", renameColumn(serverKeyColumnUsage.ReferencedTableName, serverKeyColumnUsage.ReferencedColumnName)))
+        }
+
+        if isContinue && len(columnNames) > 0 {
+            foreignKeys = append(foreignKeys, fmt.Sprintf("  CONSTRAINT `%s` FOREIGN KEY (%s) REFERENCES `%s` (%s) ON UPDATE %s ON DELETE %s",
+                renameIndex(c.serverTable.TableName, serverReferential.ConstraintName),
+                strings.Join(columnNames, ","),
+                renameTable(serverReferential.ReferencedTableName),
+                strings.Join(referencedColumnNames, ","),
+                serverReferential.UpdateRule,
+                serverReferential.DeleteRule,
+            ))
+        }
+    }
+
+    return foreignKeys
+}
+
 // createUniqueKey SQLite CREATE UNIQUE INDEX 语句。
 func (c *Converter) createUniqueKey(indexName string, statisticMap map[int]Statistic) string {
-    if idx, ok := existIndexMap[indexName]; ok {
-        atomic.AddInt32(idx, 1)
-        indexName = fmt.Sprintf("%s%d", indexName, atomic.LoadInt32(idx))
-    } else {
-        existIndexMap[indexName] = new(int32)
+    if c.isFunctionalIndex(indexName, statisticMap) {
+        return ""
     }
+    sqliteIndexName := c.getIndexName(renameIndex(c.serverTable.TableName, indexName))
 
     var seqInIndexSort []int
     var columnNames []string
@@ -279,11 +1138,56 @@ func (c *Converter) createUniqueKey(indexName string, statisticMap map[int]Stati
     sort.Ints(seqInIndexSort)
 
     for _, seqInIndex := range seqInIndexSort {
-        if gutil.InArray(statisticMap[seqInIndex].ColumnName, c.ignoreTable.Columns) {
-            glog.Fatalf(`UNIQUE INDEX Column %s is not ignore.`, statisticMap[seqInIndex].ColumnName)
+        if c.isIgnoreColumn(statisticMap[seqInIndex].ColumnName) {
+            if c.plan == nil {
+                glog.Fatalf(`UNIQUE INDEX Column %s is not ignore.`, statisticMap[seqInIndex].ColumnName)
+            }
+            c.warnf("表 `%s` 唯一索引 `%s` 字段 `%s` 不能忽略，转换时将终止。", c.serverTable.TableName, indexName, statisticMap[seqInIndex].ColumnName)
+        }
+        columnNames = append(columnNames, fmt.Sprintf("`%s`", c.renameColumn(statisticMap[seqInIndex].ColumnName)))
+    }
+
+    return fmt.Sprintf("CREATE UNIQUE INDEX `%s` ON `%s` (%s);", sqliteIndexName, c.sqliteTableName, strings.Join(columnNames, ","))
+}
+
+// createIndex SQLite CREATE INDEX 语句。
+func (c *Converter) createIndex(indexName string, statisticMap map[int]Statistic) string {
+    var columnNames []string
+
+    if statisticMap[1].IndexType != "BTREE" {
+        c.warnf("表 `%s` 索引 `%s` 类型 %s 不支持转换。", c.serverTable.TableName, indexName, statisticMap[1].IndexType)
+        return ""
+    }
+    if c.isFunctionalIndex(indexName, statisticMap) {
+        return ""
+    }
+
+    for _, columnName := range c.getIndexColumns(statisticMap) {
+        if c.isIgnoreColumn(columnName) {
+            c.warnf("表 `%s` 索引 `%s` 字段 `%s` 已忽略，跳过该索引。", c.serverTable.TableName, indexName, columnName)
+            return ""
+        }
+        columnNames = append(columnNames, fmt.Sprintf("`%s`", c.renameColumn(columnName)))
+    }
+
+    return fmt.Sprintf("CREATE INDEX `%s` ON `%s` (%s);", c.getIndexName(renameIndex(c.serverTable.TableName, indexName)), c.sqliteTableName, strings.Join(columnNames, ","))
+}
+
+// isFunctionalIndex 是否为函数索引（MySQL 8 函数键部分 COLUMN_NAME 为 NULL），函数索引不转换。
+func (c *Converter) isFunctionalIndex(indexName string, statisticMap map[int]Statistic) bool {
+    for _, statistic := range statisticMap {
+        if statistic.ColumnName == "" || statistic.EXPRESSION.Valid {
+            c.warnf("表 `%s` 索引 `%s` 为函数索引，不支持转换。", c.serverTable.TableName, indexName)
+            return true
         }
-        columnNames = append(columnNames, fmt.Sprintf("`%s`", statisticMap[seqInIndex].ColumnName))
     }
+    return false
+}
 
-    return fmt.Sprintf("CREATE UNIQUE INDEX `%s` ON `%s` (%s);", indexName, c.serverTable.TableName, strings.Join(columnNames, ","))
+// getIndexName SQLite 索引名（数据库内唯一）: 多个表存在同名索引时加表名前缀，与转换顺序无关。
+func (c *Converter) getIndexName(indexName string) string {
+    if len(indexTables[strings.ToLower(indexName)]) > 1 {
+        return fmt.Sprintf("%s_%s", c.sqliteTableName, indexName)
+    }
+    return indexName
 }
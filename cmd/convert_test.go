@@ -0,0 +1,592 @@
+package cmd
+
+import (
+    "bytes"
+    "database/sql"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+
+    "gorm.io/gorm"
+)
+
+func TestGetKeysetWhere(t *testing.T) {
+    tests := []struct {
+        name     string
+        keys     []string
+        lastKeys []any
+        where    string
+        args     []any
+    }{
+        {
+            name:     "single",
+            keys:     []string{"id"},
+            lastKeys: []any{int64(10)},
+            where:    "(`id` > ?)",
+            args:     []any{int64(10)},
+        },
+        {
+            name:     "composite",
+            keys:     []string{"a", "b", "c"},
+            lastKeys: []any{1, "x", 3},
+            where:    "(`a` > ?) OR (`a` = ? AND `b` > ?) OR (`a` = ? AND `b` = ? AND `c` > ?)",
+            args:     []any{1, 1, "x", 1, "x", 3},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := &Converter{serverTableKeys: tt.keys}
+            where, args := c.getKeysetWhere(tt.lastKeys)
+            if where != tt.where {
+                t.Errorf("where = %q, want %q", where, tt.where)
+            }
+            if !reflect.DeepEqual(args, tt.args) {
+                t.Errorf("args = %v, want %v", args, tt.args)
+            }
+        })
+    }
+}
+
+func TestGetDecimalDataType(t *testing.T) {
+    defer func(mode string, tables []*DecimalTable) {
+        decimalMode, decimalTables = mode, tables
+    }(decimalMode, decimalTables)
+
+    decimalTables = []*DecimalTable{
+        {Table: "order", Mode: DecimalText},
+        {Table: "player", Columns: []string{"gold"}, Mode: DecimalInteger},
+    }
+    tests := []struct {
+        mode   string
+        table  string
+        column string
+        want   string
+    }{
+        {mode: DecimalReal, table: "item", column: "price", want: "REAL"},
+        {mode: DecimalText, table: "item", column: "price", want: "TEXT"},
+        {mode: DecimalInteger, table: "item", column: "price", want: "INTEGER"},
+        {mode: DecimalReal, table: "order", column: "amount", want: "TEXT"},
+        {mode: DecimalReal, table: "player", column: "gold", want: "INTEGER"},
+        {mode: DecimalText, table: "player", column: "score", want: "TEXT"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.mode+"/"+tt.table+"."+tt.column, func(t *testing.T) {
+            decimalMode = tt.mode
+            c := &Converter{serverTable: &Table{TableName: tt.table}}
+            if got := c.getDecimalDataType(Column{ColumnName: tt.column}); got != tt.want {
+                t.Errorf("getDecimalDataType() = %s, want %s", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestScaleDecimal(t *testing.T) {
+    tests := []struct {
+        s       string
+        scale   int64
+        want    int64
+        wantErr bool
+    }{
+        {s: "12.34", scale: 3, want: 12340},
+        {s: "-12.34", scale: 2, want: -1234},
+        {s: "+0.5", scale: 1, want: 5},
+        {s: "7", scale: 2, want: 700},
+        {s: "1.2300", scale: 2, want: 123},
+        {s: "1.234", scale: 2, wantErr: true},
+        {s: "99999999999999999999", scale: 0, wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.s, func(t *testing.T) {
+            got, err := scaleDecimal(tt.s, tt.scale)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("scaleDecimal() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if err == nil && got != tt.want {
+                t.Errorf("scaleDecimal() = %d, want %d", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestGetCheck(t *testing.T) {
+    tests := []struct {
+        name       string
+        columnType string
+        want       string
+        valid      []string
+        invalid    []string
+    }{
+        {
+            name:       "enum",
+            columnType: "enum('a','b''c')",
+            want:       " CHECK (`v` IN ('','a','b''c'))",
+            valid:      []string{"", "a", "b'c"},
+            invalid:    []string{"c", "a,b"},
+        },
+        {
+            name:       "set",
+            columnType: "set('x','y')",
+            want:       " CHECK (`v` = '' OR (replace(replace(replace(',' || `v` || ',', ',x,', ',,'), ',y,', ',,'), ',', '') = '' AND instr(',' || `v` || ',', ',,') = 0))",
+            valid:      []string{"", "x", "y", "x,y"},
+            invalid:    []string{"z", "x,z", "xy", ",x", "x,", "x,,y"},
+        },
+        {
+            name:       "varchar",
+            columnType: "varchar(10)",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dataType, _, _ := strings.Cut(tt.columnType, "(")
+            c := &Converter{serverTable: &Table{TableName: "t"}}
+            check := c.getCheck(Column{ColumnName: "v", DataType: dataType, ColumnType: tt.columnType})
+            if check != tt.want {
+                t.Fatalf("getCheck() = %q, want %q", check, tt.want)
+            }
+            if check == "" {
+                return
+            }
+
+            s, err := NewSQLiteWriter(filepath.Join(t.TempDir(), "check.db"))
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer s.End()
+            if err = s.db.Exec("CREATE TABLE `t` (`v` TEXT" + check + ")").Error; err != nil {
+                t.Fatal(err)
+            }
+            for _, v := range tt.valid {
+                if err = s.db.Exec("INSERT INTO `t` (`v`) VALUES (?)", v).Error; err != nil {
+                    t.Errorf("插入 %q 失败: %v", v, err)
+                }
+            }
+            for _, v := range tt.invalid {
+                if err = s.db.Exec("INSERT INTO `t` (`v`) VALUES (?)", v).Error; err == nil {
+                    t.Errorf("插入 %q 应违反 CHECK", v)
+                }
+            }
+        })
+    }
+}
+
+func TestGetChunkBounds(t *testing.T) {
+    defer func(size int64, workers int) {
+        chunkSize, chunkWorkers = size, workers
+    }(chunkSize, chunkWorkers)
+
+    s, err := NewSQLiteWriter(filepath.Join(t.TempDir(), "server.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer s.End()
+    for _, statement := range []string{
+        "ATTACH DATABASE ':memory:' AS `game`",
+        "CREATE TABLE `game`.`player` (`id` INTEGER PRIMARY KEY, `name` TEXT)",
+        "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100) INSERT INTO `game`.`player` SELECT i * 3, 'p' FROM n",
+    } {
+        if err = s.db.Exec(statement).Error; err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    tests := []struct {
+        name    string
+        size    int64
+        workers int
+        keyType string
+        want    []int64
+    }{
+        {name: "chunks", size: 30, workers: 4, keyType: "INTEGER", want: []int64{78, 153, 228}},
+        {name: "small table", size: 100, workers: 4, keyType: "INTEGER"},
+        {name: "disabled", size: 0, workers: 4, keyType: "INTEGER"},
+        {name: "single worker", size: 30, workers: 1, keyType: "INTEGER"},
+        {name: "text key", size: 30, workers: 4, keyType: "TEXT"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            chunkSize, chunkWorkers = tt.size, tt.workers
+            c := &Converter{
+                serverDbConfig:     &DbConfig{Database: "game"},
+                serverDb:           s.db,
+                serverTable:        &Table{TableName: "player", TableRows: sql.NullInt64{Int64: 100, Valid: true}},
+                serverTableColumns: []*MySQL2SQLiteColumn{{ColumnName: "id", DataType: "INT", SQLiteDataType: tt.keyType}},
+                serverTableKeys:    []string{"id"},
+            }
+            bounds := c.getChunkBounds()
+            if !reflect.DeepEqual(bounds, tt.want) {
+                t.Fatalf("getChunkBounds() = %v, want %v", bounds, tt.want)
+            }
+
+            // 各分块互不重叠且覆盖全表。
+            var total int64
+            for i := 0; i <= len(bounds); i++ {
+                var count int64
+                if err := s.db.Table("`game`.`player`").Scopes(c.getChunkScope(bounds, i)).Count(&count).Error; err != nil {
+                    t.Fatal(err)
+                }
+                total += count
+            }
+            if total != 100 {
+                t.Errorf("分块行数合计 %d, want 100", total)
+            }
+        })
+    }
+}
+
+// recordWriter 记录 Converter 的输出。
+type recordWriter struct {
+    statements []string
+    rows       [][]any
+}
+
+func (w *recordWriter) Create(tableName string, statements []string) error {
+    w.statements = append(w.statements, statements...)
+    return nil
+}
+
+func (w *recordWriter) Insert(tableName string, columns []*MySQL2SQLiteColumn, rows [][]any) error {
+    w.rows = append(w.rows, rows...)
+    return nil
+}
+
+func (w *recordWriter) Upsert(tableName string, columns []*MySQL2SQLiteColumn, keys []string, rows [][]any) error {
+    return w.Insert(tableName, columns, rows)
+}
+
+func (w *recordWriter) SetWatermark(tableName string, columnName string, watermark string) error {
+    return nil
+}
+
+// newTestServerDb 以 SQLite 模拟 information_schema，数据表位于附加数据库 `game`。
+func newTestServerDb(t *testing.T, statements ...string) *gorm.DB {
+    s, err := NewSQLiteWriter(filepath.Join(t.TempDir(), "information_schema.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() {
+        s.End()
+    })
+
+    for name, model := range map[string]any{
+        "COLUMNS":                 &Column{},
+        "STATISTICS":              &Statistic{},
+        "VIEWS":                   &View{},
+        "REFERENTIAL_CONSTRAINTS": &ReferentialConstraints{},
+        "KEY_COLUMN_USAGE":        &KeyColumnUsage{},
+    } {
+        if err = s.db.Table(name).AutoMigrate(model); err != nil {
+            t.Fatal(err)
+        }
+    }
+    statements = append([]string{"ATTACH DATABASE ':memory:' AS `game`"}, statements...)
+    for _, statement := range statements {
+        if err = s.db.Exec(statement).Error; err != nil {
+            t.Fatal(err)
+        }
+    }
+    return s.db
+}
+
+// newTestPlayerDb 模拟数据库 `game`，表 `player` 含 id 为 1..10 的数据行。
+func newTestPlayerDb(t *testing.T) *gorm.DB {
+    serverDb := newTestServerDb(t,
+        "CREATE TABLE `game`.`player` (`id` INTEGER PRIMARY KEY, `name` TEXT NOT NULL)",
+        "INSERT INTO `game`.`player` SELECT i, char(96 + i) FROM (WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10) SELECT i FROM n)",
+    )
+    err := serverDb.Table("COLUMNS").Create([]Column{
+        {TableSchema: "game", TableName: "player", ColumnName: "id", OrdinalPosition: 1, IsNullable: "NO", DataType: "int", ColumnType: "int"},
+        {TableSchema: "game", TableName: "player", ColumnName: "name", OrdinalPosition: 2, IsNullable: "NO", DataType: "varchar", ColumnType: "varchar(32)"},
+    }).Error
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = serverDb.Table("STATISTICS").Create(&Statistic{TableSchema: "game", TableName: "player", IndexName: "PRIMARY", SeqInIndex: 1, ColumnName: "id", IndexType: "BTREE"}).Error
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return serverDb
+}
+
+func TestConverterStart(t *testing.T) {
+    defer func(data, create bool) {
+        noData, noCreate = data, create
+    }(noData, noCreate)
+
+    serverDb := newTestPlayerDb(t)
+
+    tests := []struct {
+        name       string
+        noData     bool
+        noCreate   bool
+        statements int
+        rows       int
+    }{
+        {name: "all", statements: 2, rows: 10},
+        {name: "no data", noData: true, statements: 2},
+        {name: "no create", noCreate: true, rows: 10},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            noData, noCreate = tt.noData, tt.noCreate
+            w := &recordWriter{}
+            c := NewConverter(&DbConfig{Database: "game"}, serverDb, &Table{TableName: "player", TableType: "BASE TABLE"}, &IgnoreTable{}, w)
+
+            wg.Add(1)
+            ch <- true
+            c.Start()
+
+            if len(w.statements) != tt.statements {
+                t.Errorf("statements = %q, want %d", w.statements, tt.statements)
+            }
+            if len(w.rows) != tt.rows {
+                t.Errorf("rows = %v, want %d", w.rows, tt.rows)
+            }
+        })
+    }
+}
+
+func TestConverterFilter(t *testing.T) {
+    defer func(m map[string]*FilterTable) {
+        filterMap = m
+    }(filterMap)
+
+    serverDb := newTestPlayerDb(t)
+
+    tests := []struct {
+        name        string
+        filterTable *FilterTable
+        want        []int64
+    }{
+        {name: "none", want: []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+        {name: "where", filterTable: &FilterTable{Where: "`id` % 3 = 0"}, want: []int64{3, 6, 9}},
+        {name: "order by", filterTable: &FilterTable{Where: "`id` > 7", OrderBy: "`id` DESC"}, want: []int64{10, 9, 8}},
+        {name: "limit", filterTable: &FilterTable{Limit: 2}, want: []int64{1, 2}},
+        {name: "where order by limit", filterTable: &FilterTable{Where: "`name` <> 'j'", OrderBy: "`id` DESC", Limit: 3}, want: []int64{9, 8, 7}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            filterMap = map[string]*FilterTable{"player": tt.filterTable}
+            w := &recordWriter{}
+            c := NewConverter(&DbConfig{Database: "game"}, serverDb, &Table{TableName: "player", TableType: "BASE TABLE"}, &IgnoreTable{}, w)
+
+            wg.Add(1)
+            ch <- true
+            c.Start()
+
+            var got []int64
+            for _, row := range w.rows {
+                got = append(got, row[0].(int64))
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("rows = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestConvert(t *testing.T) {
+    defer func(names []string, m map[string]*streamWriter) {
+        sqlTableNames, sqlTableMap = names, m
+    }(sqlTableNames, sqlTableMap)
+    sqlTableNames, sqlTableMap = nil, make(map[string]*streamWriter)
+
+    serverDb := newTestPlayerDb(t)
+    var buf bytes.Buffer
+    convert(NewSqlWriter(&buf), &DbConfig{Database: "game"}, serverDb, []*Table{{TableName: "player", TableType: "BASE TABLE"}})
+
+    s, err := NewSQLiteWriter(filepath.Join(t.TempDir(), "convert.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer s.End()
+    sqlDb, err := s.db.DB()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err = sqlDb.Exec(buf.String()); err != nil {
+        t.Fatalf("执行 SQL 失败: %v\n%s", err, buf.String())
+    }
+    var count int64
+    if err = s.db.Table("player").Count(&count).Error; err != nil || count != 10 {
+        t.Errorf("player 行数 = %d, %v, want 10", count, err)
+    }
+}
+
+func TestCreateView(t *testing.T) {
+    defer func(views []string) {
+        failedViews = views
+    }(failedViews)
+
+    serverDb := newTestServerDb(t)
+    err := serverDb.Table("VIEWS").Create([]View{
+        {TableSchema: "game", TableName: "v_ok", ViewDefinition: "select `game`.`player`.`id` AS `id` from `game`.`player`"},
+        {TableSchema: "game", TableName: "v_lock", ViewDefinition: "select `game`.`player`.`id` AS `id` from `game`.`player` lock in share mode"},
+        {TableSchema: "game", TableName: "v_rand", ViewDefinition: "select rand() AS `r`"},
+    }).Error
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name   string
+        create bool
+    }{
+        {name: "v_ok", create: true},
+        {name: "v_lock"},
+        {name: "v_rand"},
+        {name: "v_missing"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            failedViews = nil
+            w := &recordWriter{}
+            c := NewConverter(&DbConfig{Database: "game"}, serverDb, &Table{TableName: tt.name, TableType: "VIEW"}, &IgnoreTable{}, w)
+            c.createView()
+
+            if created := len(w.statements) > 0; created != tt.create {
+                t.Errorf("created = %v, want %v: %q", created, tt.create, w.statements)
+            }
+            if failed := len(failedViews) > 0; failed == tt.create {
+                t.Errorf("failedViews = %q", failedViews)
+            }
+        })
+    }
+}
+
+func TestGetDefault(t *testing.T) {
+    tests := []struct {
+        name           string
+        column         Column
+        sqliteDataType string
+        want           string
+    }{
+        {name: "none", column: Column{DataType: "int"}, sqliteDataType: "INTEGER"},
+        {name: "integer", column: Column{DataType: "int", ColumnDefault: sql.NullString{String: "-1", Valid: true}}, sqliteDataType: "INTEGER", want: " DEFAULT -1"},
+        {name: "text", column: Column{DataType: "varchar", ColumnDefault: sql.NullString{String: "it's", Valid: true}}, sqliteDataType: "TEXT", want: " DEFAULT 'it''s'"},
+        {name: "empty text", column: Column{DataType: "varchar", ColumnDefault: sql.NullString{Valid: true}}, sqliteDataType: "TEXT", want: " DEFAULT ''"},
+        {name: "scaled decimal", column: Column{DataType: "decimal", NumericScale: sql.NullInt64{Int64: 2, Valid: true}, ColumnDefault: sql.NullString{String: "1.50", Valid: true}}, sqliteDataType: "INTEGER", want: " DEFAULT 150"},
+        {name: "current timestamp", column: Column{DataType: "datetime", EXTRA: "DEFAULT_GENERATED", ColumnDefault: sql.NullString{String: "CURRENT_TIMESTAMP", Valid: true}}, sqliteDataType: "TEXT", want: " DEFAULT CURRENT_TIMESTAMP"},
+        {name: "expression", column: Column{DataType: "varchar", EXTRA: "DEFAULT_GENERATED", ColumnDefault: sql.NullString{String: "concat(_utf8mb4\\'a\\',_utf8mb4\\'b\\')", Valid: true}}, sqliteDataType: "TEXT", want: " DEFAULT ('a' || 'b')"},
+        {name: "bit", column: Column{DataType: "bit", NumericPrecision: sql.NullInt64{Int64: 3, Valid: true}, ColumnDefault: sql.NullString{String: "b'101'", Valid: true}}, sqliteDataType: "BLOB", want: " DEFAULT X'05'"},
+        {name: "bit 16", column: Column{DataType: "bit", NumericPrecision: sql.NullInt64{Int64: 16, Valid: true}, ColumnDefault: sql.NullString{String: "b'1'", Valid: true}}, sqliteDataType: "BLOB", want: " DEFAULT X'0001'"},
+        {name: "bit 64", column: Column{DataType: "bit", NumericPrecision: sql.NullInt64{Int64: 64, Valid: true}, ColumnDefault: sql.NullString{String: "b'1111111111111111111111111111111111111111111111111111111111111111'", Valid: true}}, sqliteDataType: "BLOB", want: " DEFAULT X'FFFFFFFFFFFFFFFF'"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := &Converter{serverDbConfig: &DbConfig{Database: "game"}, serverTable: &Table{TableName: "t"}}
+            if got := c.getDefault(tt.column, tt.sqliteDataType); got != tt.want {
+                t.Errorf("getDefault() = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestGetGenerated(t *testing.T) {
+    defer func(generated bool, m map[string]*IgnoreTable) {
+        jsonGenerated, icMap = generated, m
+    }(jsonGenerated, icMap)
+    icMap = map[string]*IgnoreTable{"t": {Table: "t", Columns: []string{"secret"}}}
+
+    primary := []Statistic{{IndexName: "PRIMARY", SeqInIndex: 1, ColumnName: "id"}}
+    tests := []struct {
+        name          string
+        column        Column
+        jsonGenerated bool
+        want          string
+    }{
+        {
+            name:   "plain column",
+            column: Column{ColumnName: "a"},
+        },
+        {
+            name:   "virtual",
+            column: Column{ColumnName: "c", EXTRA: "VIRTUAL GENERATED", GenerationExpression: "(`a` + `b`)"},
+            want:   " GENERATED ALWAYS AS (`a` + `b`) VIRTUAL",
+        },
+        {
+            name:   "stored",
+            column: Column{ColumnName: "c", EXTRA: "STORED GENERATED", GenerationExpression: "concat(`a`,_utf8mb4\\'-\\')"},
+            want:   " GENERATED ALWAYS AS (`a` || '-') STORED",
+        },
+        {
+            name:   "json path",
+            column: Column{ColumnName: "name", EXTRA: "VIRTUAL GENERATED", GenerationExpression: "json_unquote(json_extract(`doc`,_utf8mb4\\'$.name\\'))"},
+        },
+        {
+            name:          "json path with --json-generated",
+            column:        Column{ColumnName: "name", EXTRA: "VIRTUAL GENERATED", GenerationExpression: "json_unquote(json_extract(`doc`,_utf8mb4\\'$.name\\'))"},
+            jsonGenerated: true,
+            want:          " GENERATED ALWAYS AS (json_extract(`doc`, '$.name')) VIRTUAL",
+        },
+        {
+            name:   "primary key",
+            column: Column{ColumnName: "id", EXTRA: "STORED GENERATED", GenerationExpression: "(`a` + 1)"},
+        },
+        {
+            name:   "unsupported function",
+            column: Column{ColumnName: "c", EXTRA: "VIRTUAL GENERATED", GenerationExpression: "rand()"},
+        },
+        {
+            name:   "ignored column",
+            column: Column{ColumnName: "c", EXTRA: "VIRTUAL GENERATED", GenerationExpression: "(`secret` + 1)"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            jsonGenerated = tt.jsonGenerated
+            c := &Converter{
+                serverDbConfig: &DbConfig{Database: "game"},
+                serverTable:    &Table{TableName: "t"},
+                plan:           &TablePlan{},
+            }
+            if got := c.getGenerated(tt.column, primary); got != tt.want {
+                t.Errorf("getGenerated() = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestGetChunkBoundsSnapshot(t *testing.T) {
+    defer func(size int64, workers int, s *Snapshot) {
+        chunkSize, chunkWorkers, snapshot = size, workers, s
+    }(chunkSize, chunkWorkers, snapshot)
+    chunkSize, chunkWorkers = 30, 4
+
+    // serverDb 与快照中的数据不同，分块边界应按快照计算。
+    serverDb := newTestServerDb(t,
+        "CREATE TABLE `game`.`player` (`id` INTEGER PRIMARY KEY)",
+        "INSERT INTO `game`.`player` VALUES (1), (1000)",
+    )
+    session := newTestServerDb(t,
+        "CREATE TABLE `game`.`player` (`id` INTEGER PRIMARY KEY)",
+        "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100) INSERT INTO `game`.`player` SELECT i * 3 FROM n",
+    )
+    snapshot = &Snapshot{sessions: make(chan *gorm.DB, 1)}
+    snapshot.sessions <- session
+
+    c := &Converter{
+        serverDbConfig:     &DbConfig{Database: "game"},
+        serverDb:           serverDb,
+        serverTable:        &Table{TableName: "player", TableRows: sql.NullInt64{Int64: 100, Valid: true}},
+        serverTableColumns: []*MySQL2SQLiteColumn{{ColumnName: "id", DataType: "INT", SQLiteDataType: "INTEGER"}},
+        serverTableKeys:    []string{"id"},
+    }
+    if bounds := c.getChunkBounds(); !reflect.DeepEqual(bounds, []int64{78, 153, 228}) {
+        t.Errorf("getChunkBounds() = %v, want [78 153 228]", bounds)
+    }
+    if len(snapshot.sessions) != 1 {
+        t.Error("快照连接未归还")
+    }
+}
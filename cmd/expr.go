@@ -0,0 +1,656 @@
+package cmd
+
+import (
+    "fmt"
+    "strings"
+    "unicode"
+
+    "github.com/camry/g/gutil"
+)
+
+const (
+    tokenSpace  = iota // 空白
+    tokenIdent         // 标识符、关键字
+    tokenQuoted        // `标识符`
+    tokenString        // 字符串
+    tokenNumber        // 数字
+    tokenSymbol        // 运算符、标点
+)
+
+// token 词法单元。
+type token struct {
+    kind  int
+    text  string
+    value string
+}
+
+// sqlKeywords 后跟括号时不视为函数调用的关键字。
+var sqlKeywords = []string{
+    "AND", "OR", "NOT", "XOR", "IN", "IS", "LIKE", "EXISTS", "ON", "USING", "AS", "FROM", "WHERE", "JOIN",
+    "SELECT", "WHEN", "THEN", "ELSE", "CASE", "END", "BETWEEN", "BY", "VALUES", "ANY", "ALL", "SOME",
+    "DISTINCT", "UNION", "HAVING", "LIMIT", "OFFSET", "REGEXP",
+}
+
+// sqliteFunctions 可直接对应 SQLite 函数的 MySQL 函数。
+var sqliteFunctions = map[string]string{
+    "ABS":              "abs",
+    "AVG":              "avg",
+    "CHAR_LENGTH":      "length",
+    "CHARACTER_LENGTH": "length",
+    "COALESCE":         "coalesce",
+    "COUNT":            "count",
+    "GREATEST":         "max",
+    "HEX":              "hex",
+    "IF":               "iif",
+    "IFNULL":           "ifnull",
+    "INSTR":            "instr",
+    "JSON_ARRAY":       "json_array",
+    "JSON_EXTRACT":     "json_extract",
+    "JSON_OBJECT":      "json_object",
+    "JSON_TYPE":        "json_type",
+    "JSON_VALID":       "json_valid",
+    "LCASE":            "lower",
+    "LEAST":            "min",
+    "LOWER":            "lower",
+    "LTRIM":            "ltrim",
+    "MAX":              "max",
+    "MID":              "substr",
+    "MIN":              "min",
+    "NULLIF":           "nullif",
+    "REPLACE":          "replace",
+    "ROUND":            "round",
+    "RTRIM":            "rtrim",
+    "SUBSTR":           "substr",
+    "SUBSTRING":        "substr",
+    "SUM":              "sum",
+    "TRIM":             "trim",
+    "UCASE":            "upper",
+    "UPPER":            "upper",
+}
+
+// sqliteCastTypes CAST 类型映射。
+var sqliteCastTypes = map[string]string{
+    "SIGNED":   "INTEGER",
+    "UNSIGNED": "INTEGER",
+    "INT":      "INTEGER",
+    "INTEGER":  "INTEGER",
+    "CHAR":     "TEXT",
+    "NCHAR":    "TEXT",
+    "JSON":     "TEXT",
+    "DECIMAL":  "REAL",
+    "DOUBLE":   "REAL",
+    "FLOAT":    "REAL",
+    "REAL":     "REAL",
+    "BINARY":   "BLOB",
+}
+
+// exprTranslator MySQL 表达式转换为 SQLite 方言。
+type exprTranslator struct {
+    database string
+    table    string            // 表达式所属的表，非空时字段按该表重命名
+    aliases  map[string]string // 视图中的表别名
+}
+
+// translateExpr 转换 MySQL 表达式（视图定义、默认值、生成列）为 SQLite 方言，table 为生成列所属的表。
+func translateExpr(expr string, database string, table string) (string, error) {
+    tokens, err := tokenize(expr)
+    if err != nil {
+        return "", err
+    }
+    return (&exprTranslator{database: database, table: table, aliases: getAliases(tokens)}).translate(tokens)
+}
+
+// tokenize MySQL 表达式词法分析。
+func tokenize(expr string) ([]token, error) {
+    var (
+        tokens []token
+        rs     = []rune(expr)
+    )
+
+    for i := 0; i < len(rs); {
+        r := rs[i]
+        switch {
+        case unicode.IsSpace(r):
+            j := i
+            for j < len(rs) && unicode.IsSpace(rs[j]) {
+                j++
+            }
+            tokens = append(tokens, token{kind: tokenSpace, text: " "})
+            i = j
+        case r == '`':
+            j := i + 1
+            for ; j < len(rs); j++ {
+                if rs[j] == '`' {
+                    if j+1 < len(rs) && rs[j+1] == '`' {
+                        j++
+                        continue
+                    }
+                    break
+                }
+            }
+            if j >= len(rs) {
+                return nil, fmt.Errorf("标识符未闭合: %s", string(rs[i:]))
+            }
+            tokens = append(tokens, token{
+                kind:  tokenQuoted,
+                text:  string(rs[i : j+1]),
+                value: strings.ReplaceAll(string(rs[i+1:j]), "``", "`"),
+            })
+            i = j + 1
+        case r == '\'' || r == '"':
+            var value strings.Builder
+            j := i + 1
+            for ; j < len(rs); j++ {
+                if rs[j] == '\\' && j+1 < len(rs) {
+                    j++
+                    switch rs[j] {
+                    case 'n':
+                        value.WriteRune('\n')
+                    case 't':
+                        value.WriteRune('\t')
+                    case 'r':
+                        value.WriteRune('\r')
+                    case '0':
+                        value.WriteRune(0)
+                    case 'b':
+                        value.WriteRune('\b')
+                    case 'Z':
+                        value.WriteRune(0x1a)
+                    case '%', '_':
+                        // \% \_ 保留反斜杠，供 LIKE 转义。
+                        value.WriteRune('\\')
+                        value.WriteRune(rs[j])
+                    default:
+                        value.WriteRune(rs[j])
+                    }
+                    continue
+                }
+                if rs[j] == r {
+                    if j+1 < len(rs) && rs[j+1] == r {
+                        value.WriteRune(r)
+                        j++
+                        continue
+                    }
+                    break
+                }
+                value.WriteRune(rs[j])
+            }
+            if j >= len(rs) {
+                return nil, fmt.Errorf("字符串未闭合: %s", string(rs[i:]))
+            }
+            tokens = append(tokens, token{
+                kind:  tokenString,
+                text:  quoteString(value.String()),
+                value: value.String(),
+            })
+            i = j + 1
+        case unicode.IsDigit(r) || (r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+            j := i
+            for j < len(rs) && (unicode.IsDigit(rs[j]) || unicode.IsLetter(rs[j]) || rs[j] == '.') {
+                j++
+            }
+            tokens = append(tokens, token{kind: tokenNumber, text: string(rs[i:j])})
+            i = j
+        case unicode.IsLetter(r) || r == '_' || r == '$':
+            j := i
+            for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_' || rs[j] == '$') {
+                j++
+            }
+            tokens = append(tokens, token{kind: tokenIdent, text: string(rs[i:j])})
+            i = j
+        default:
+            text := string(r)
+            for _, symbol := range []string{"<=>", "->>", "->", "<=", ">=", "<>", "!=", "||", "&&", "<<", ">>", ":="} {
+                if strings.HasPrefix(string(rs[i:]), symbol) {
+                    text = symbol
+                    break
+                }
+            }
+            tokens = append(tokens, token{kind: tokenSymbol, text: text})
+            i += len([]rune(text))
+        }
+    }
+
+    return tokens, nil
+}
+
+// translate 转换词法单元序列。
+func (t *exprTranslator) translate(tokens []token) (string, error) {
+    var sb strings.Builder
+
+    for i := 0; i < len(tokens); i++ {
+        tk := tokens[i]
+        switch tk.kind {
+        case tokenIdent, tokenQuoted:
+            // 去除数据库限定符: `db`.`table` -> `table`
+            if t.database != "" && (tk.value == t.database || tk.text == t.database) {
+                if j := nextToken(tokens, i); j < len(tokens) && tokens[j].text == "." {
+                    i = j
+                    continue
+                }
+            }
+            if tk.kind == tokenQuoted {
+                var quoted string
+                quoted, i = t.rename(tokens, i)
+                sb.WriteString(quoted)
+                continue
+            }
+
+            upper := strings.ToUpper(tk.text)
+
+            // 字符集前缀: _utf8mb4'abc' -> 'abc'
+            if strings.HasPrefix(tk.text, "_") && i+1 < len(tokens) && tokens[i+1].kind == tokenString {
+                continue
+            }
+            // 十六进制字面量: X'0A'
+            if upper == "X" && i+1 < len(tokens) && tokens[i+1].kind == tokenString {
+                sb.WriteString(fmt.Sprintf("X'%s'", tokens[i+1].value))
+                i++
+                continue
+            }
+            if (upper == "B" || upper == "N") && i+1 < len(tokens) && tokens[i+1].kind == tokenString {
+                if upper == "B" {
+                    return "", fmt.Errorf("不支持位字面量 b'%s'", tokens[i+1].value)
+                }
+                continue
+            }
+            // 排序规则: COLLATE utf8mb4_bin
+            if upper == "COLLATE" {
+                if j := nextToken(tokens, i); j < len(tokens) {
+                    i = j
+                }
+                continue
+            }
+
+            if j := nextToken(tokens, i); j < len(tokens) && tokens[j].text == "(" && !gutil.InArray(upper, sqlKeywords) {
+                end, err := matchParen(tokens, j)
+                if err != nil {
+                    return "", err
+                }
+                call, err := t.function(upper, splitArgs(tokens[j+1:end]))
+                if err != nil {
+                    return "", err
+                }
+                sb.WriteString(call)
+                i = end
+                continue
+            }
+
+            switch upper {
+            case "LOCALTIME", "LOCALTIMESTAMP":
+                sb.WriteString("CURRENT_TIMESTAMP")
+            case "DIV", "INTERVAL":
+                return "", fmt.Errorf("不支持运算符 %s", tk.text)
+            default:
+                sb.WriteString(tk.text)
+            }
+        case tokenSymbol:
+            switch tk.text {
+            case "(":
+                end, err := matchParen(tokens, i)
+                if err != nil {
+                    return "", err
+                }
+                inner, err := t.translate(tokens[i+1 : end])
+                if err != nil {
+                    return "", err
+                }
+                sb.WriteString("(" + inner + ")")
+                i = end
+            case "<=>":
+                sb.WriteString(" IS ")
+            case "&&":
+                sb.WriteString(" AND ")
+            case "||":
+                sb.WriteString(" OR ")
+            case "@", ":=":
+                return "", fmt.Errorf("不支持变量")
+            default:
+                sb.WriteString(tk.text)
+            }
+        case tokenString:
+            sb.WriteString(tk.text)
+            if isLikeEscaped(tokens, i) {
+                sb.WriteString(" ESCAPE '\\'")
+            }
+        default:
+            sb.WriteString(tk.text)
+        }
+    }
+
+    return sb.String(), nil
+}
+
+// isLikeEscaped LIKE 模式字符串含反斜杠且未指定 ESCAPE: MySQL 默认以 \ 转义，SQLite 需指定 ESCAPE '\'。
+func isLikeEscaped(tokens []token, i int) bool {
+    if !strings.Contains(tokens[i].value, "\\") {
+        return false
+    }
+    if j := nextToken(tokens, i); j < len(tokens) && strings.ToUpper(tokens[j].text) == "ESCAPE" {
+        return false
+    }
+
+    // 向前跳过空白及字符集前缀: LIKE _utf8mb4'...'
+    j := i - 1
+    for j >= 0 && (tokens[j].kind == tokenSpace || (tokens[j].kind == tokenIdent && strings.HasPrefix(tokens[j].text, "_"))) {
+        j--
+    }
+    return j >= 0 && strings.ToUpper(tokens[j].text) == "LIKE"
+}
+
+// rename 按配置文件 rename 重命名标识符，返回转换结果及最后一个词法单元下标:
+// `表`.`字段` 按表重命名，生成列中的 `字段` 按所属表重命名，视图中的 `表` 重命名。
+func (t *exprTranslator) rename(tokens []token, i int) (string, int) {
+    tk := tokens[i]
+    if renameConfig == nil {
+        return tk.text, i
+    }
+
+    if j := nextToken(tokens, i); j < len(tokens) && tokens[j].text == "." {
+        k := nextToken(tokens, j)
+        if k >= len(tokens) || tokens[k].kind != tokenQuoted {
+            return tk.text, i
+        }
+        if tableName, ok := t.aliases[tk.value]; ok {
+            return fmt.Sprintf("%s.%s", tk.text, quoteIdent(tokens[k], renameColumn(tableName, tokens[k].value))), k
+        }
+        if _, ok := tableNameMap[tk.value]; !ok {
+            return joinTokens(tokens[i : k+1]), k
+        }
+        return fmt.Sprintf("%s.%s",
+            quoteIdent(tk, renameTable(tk.value)),
+            quoteIdent(tokens[k], renameColumn(tk.value, tokens[k].value)),
+        ), k
+    }
+
+    if t.table != "" {
+        return quoteIdent(tk, renameColumn(t.table, tk.value)), i
+    }
+    if _, ok := tableNameMap[tk.value]; ok {
+        return quoteIdent(tk, renameTable(tk.value)), i
+    }
+    return tk.text, i
+}
+
+// getAliases 视图中的表别名: `表` `别名`、`表` AS `别名`。
+func getAliases(tokens []token) map[string]string {
+    if renameConfig == nil {
+        return nil
+    }
+
+    aliases := make(map[string]string)
+    for i, tk := range tokens {
+        if tk.kind != tokenQuoted {
+            continue
+        }
+        if _, ok := tableNameMap[tk.value]; !ok {
+            continue
+        }
+        j := nextToken(tokens, i)
+        if j < len(tokens) && tokens[j].kind == tokenIdent && strings.ToUpper(tokens[j].text) == "AS" {
+            j = nextToken(tokens, j)
+        }
+        if j < len(tokens) && tokens[j].kind == tokenQuoted {
+            aliases[tokens[j].value] = tk.value
+        }
+    }
+    return aliases
+}
+
+// quoteIdent 重命名后的 `标识符`，名称不变时保持原文。
+func quoteIdent(tk token, name string) string {
+    if name == tk.value {
+        return tk.text
+    }
+    return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
+}
+
+// function 转换函数调用。
+func (t *exprTranslator) function(name string, args [][]token) (string, error) {
+    switch name {
+    case "CAST":
+        return t.cast(args)
+    case "CONVERT":
+        if len(args) == 2 {
+            return t.cast([][]token{append(append(args[0], token{kind: tokenIdent, text: "AS"}), args[1]...)})
+        }
+        if len(args) == 1 {
+            // CONVERT(expr USING charset)
+            for i, tk := range args[0] {
+                if strings.ToUpper(tk.text) == "USING" {
+                    translated, err := t.translate(args[0][:i])
+                    return strings.TrimSpace(translated), err
+                }
+            }
+        }
+        return "", fmt.Errorf("不支持函数 CONVERT")
+    case "GROUP_CONCAT":
+        return t.groupConcat(args)
+    }
+
+    var translatedArgs []string
+    for _, arg := range args {
+        translated, err := t.translate(arg)
+        if err != nil {
+            return "", err
+        }
+        translatedArgs = append(translatedArgs, strings.TrimSpace(translated))
+    }
+
+    switch name {
+    case "CONCAT":
+        if len(translatedArgs) == 0 {
+            return "", fmt.Errorf("函数 CONCAT 缺少参数")
+        }
+        return fmt.Sprintf("(%s)", strings.Join(translatedArgs, " || ")), nil
+    case "NOW", "CURRENT_TIMESTAMP", "LOCALTIME", "LOCALTIMESTAMP", "SYSDATE":
+        return "CURRENT_TIMESTAMP", nil
+    case "CURDATE", "CURRENT_DATE":
+        return "CURRENT_DATE", nil
+    case "CURTIME", "CURRENT_TIME":
+        return "CURRENT_TIME", nil
+    case "JSON_UNQUOTE":
+        // SQLite json_extract 已返回去引号的值。
+        if len(translatedArgs) == 1 && strings.HasPrefix(translatedArgs[0], "json_extract(") {
+            return translatedArgs[0], nil
+        }
+        if len(translatedArgs) == 1 {
+            return fmt.Sprintf("json_extract(%s, '$')", translatedArgs[0]), nil
+        }
+    case "LENGTH", "OCTET_LENGTH":
+        // MySQL LENGTH 返回字节数，SQLite length 对 TEXT 返回字符数。
+        if len(translatedArgs) == 1 {
+            return fmt.Sprintf("length(CAST(%s AS BLOB))", translatedArgs[0]), nil
+        }
+    case "UNIX_TIMESTAMP":
+        if len(translatedArgs) == 0 {
+            return "CAST(strftime('%s', 'now') AS INTEGER)", nil
+        }
+        return fmt.Sprintf("CAST(strftime('%%s', %s) AS INTEGER)", translatedArgs[0]), nil
+    }
+
+    if sqliteName, ok := sqliteFunctions[name]; ok {
+        return fmt.Sprintf("%s(%s)", sqliteName, strings.Join(translatedArgs, ", ")), nil
+    }
+
+    return "", fmt.Errorf("不支持函数 %s", name)
+}
+
+// cast 转换 CAST(expr AS type)。
+func (t *exprTranslator) cast(args [][]token) (string, error) {
+    if len(args) == 1 {
+        for i := len(args[0]) - 1; i >= 0; i-- {
+            if strings.ToUpper(args[0][i].text) != "AS" {
+                continue
+            }
+            expr, err := t.translate(args[0][:i])
+            if err != nil {
+                return "", err
+            }
+            expr = strings.TrimSpace(expr)
+
+            var castType string
+            if j := nextToken(args[0], i); j < len(args[0]) {
+                castType = strings.ToUpper(args[0][j].text)
+            }
+            switch castType {
+            case "DATE":
+                return fmt.Sprintf("date(%s)", expr), nil
+            case "DATETIME":
+                return fmt.Sprintf("datetime(%s)", expr), nil
+            case "TIME":
+                return fmt.Sprintf("time(%s)", expr), nil
+            }
+            if sqliteType, ok := sqliteCastTypes[castType]; ok {
+                return fmt.Sprintf("CAST(%s AS %s)", expr, sqliteType), nil
+            }
+            return "", fmt.Errorf("不支持 CAST 类型 %s", castType)
+        }
+    }
+    return "", fmt.Errorf("不支持函数 CAST")
+}
+
+// groupConcat 转换 GROUP_CONCAT(expr SEPARATOR sep)。
+func (t *exprTranslator) groupConcat(args [][]token) (string, error) {
+    if len(args) != 1 {
+        return "", fmt.Errorf("不支持多字段 GROUP_CONCAT")
+    }
+
+    expr, separator := args[0], "','"
+    for i, tk := range args[0] {
+        switch strings.ToUpper(tk.text) {
+        case "ORDER":
+            return "", fmt.Errorf("不支持 GROUP_CONCAT ORDER BY")
+        case "SEPARATOR":
+            if j := nextToken(args[0], i); j < len(args[0]) {
+                separator = args[0][j].text
+            }
+            expr = args[0][:i]
+        }
+    }
+
+    translated, err := t.translate(expr)
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("group_concat(%s, %s)", strings.TrimSpace(translated), separator), nil
+}
+
+// isJSONPathExpr 是否为 JSON 路径表达式: json_extract(col, path)、json_unquote(...)、col->path、col->>path。
+func isJSONPathExpr(expr string) bool {
+    tokens, err := tokenize(expr)
+    if err != nil {
+        return false
+    }
+
+    isJSONPath := false
+    for i, tk := range tokens {
+        switch tk.kind {
+        case tokenIdent:
+            if j := nextToken(tokens, i); j < len(tokens) && tokens[j].text == "(" {
+                switch strings.ToUpper(tk.text) {
+                case "JSON_EXTRACT":
+                    isJSONPath = true
+                case "JSON_UNQUOTE":
+                default:
+                    return false
+                }
+            }
+        case tokenSymbol:
+            switch tk.text {
+            case "->", "->>":
+                isJSONPath = true
+            case "(", ")", ",", ".":
+            default:
+                return false
+            }
+        }
+    }
+    return isJSONPath
+}
+
+// nextToken 下一个非空白词法单元下标。
+func nextToken(tokens []token, i int) int {
+    j := i + 1
+    for j < len(tokens) && tokens[j].kind == tokenSpace {
+        j++
+    }
+    return j
+}
+
+// matchParen 匹配右括号下标。
+func matchParen(tokens []token, i int) (int, error) {
+    depth := 0
+    for j := i; j < len(tokens); j++ {
+        if tokens[j].kind != tokenSymbol {
+            continue
+        }
+        switch tokens[j].text {
+        case "(":
+            depth++
+        case ")":
+            depth--
+            if depth == 0 {
+                return j, nil
+            }
+        }
+    }
+    return 0, fmt.Errorf("括号未闭合")
+}
+
+// splitArgs 按顶层逗号拆分函数参数。
+func splitArgs(tokens []token) [][]token {
+    var (
+        args  [][]token
+        depth int
+        start int
+    )
+    for i, tk := range tokens {
+        if tk.kind != tokenSymbol {
+            continue
+        }
+        switch tk.text {
+        case "(":
+            depth++
+        case ")":
+            depth--
+        case ",":
+            if depth == 0 {
+                args = append(args, tokens[start:i])
+                start = i + 1
+            }
+        }
+    }
+    if start < len(tokens) || len(args) > 0 {
+        args = append(args, tokens[start:])
+    }
+    // 无参数: NOW()
+    if len(args) == 1 && strings.TrimSpace(joinTokens(args[0])) == "" {
+        return nil
+    }
+    return args
+}
+
+// joinTokens 拼接词法单元原文。
+func joinTokens(tokens []token) string {
+    var sb strings.Builder
+    for _, tk := range tokens {
+        sb.WriteString(tk.text)
+    }
+    return sb.String()
+}
+
+// wrapParen 表达式外层加括号（已有则保持）。
+func wrapParen(expr string) string {
+    if tokens, err := tokenize(expr); err == nil && len(tokens) > 0 && tokens[0].text == "(" {
+        if end, err := matchParen(tokens, 0); err == nil && end == len(tokens)-1 {
+            return expr
+        }
+    }
+    return fmt.Sprintf("(%s)", expr)
+}
+
+// quoteString SQLite 字符串字面量。
+func quoteString(s string) string {
+    return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+}
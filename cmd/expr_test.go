@@ -0,0 +1,193 @@
+package cmd
+
+import "testing"
+
+func TestTranslateExpr(t *testing.T) {
+    tests := []struct {
+        name    string
+        expr    string
+        want    string
+        wantErr bool
+    }{
+        {
+            name: "ifnull",
+            expr: "select `game`.`player`.`id` AS `id`,ifnull(`game`.`player`.`email`,'') AS `email` from `game`.`player`",
+            want: "select `player`.`id` AS `id`,ifnull(`player`.`email`, '') AS `email` from `player`",
+        },
+        {
+            name: "concat",
+            expr: "select concat(`p`.`name`,_utf8mb4'-',`p`.`id`) AS `label` from `game`.`player` `p`",
+            want: "select (`p`.`name` || '-' || `p`.`id`) AS `label` from `player` `p`",
+        },
+        {
+            name: "now",
+            expr: "select `game`.`player`.`id` AS `id`,now() AS `ts` from `game`.`player` where (`game`.`player`.`created_at` < now())",
+            want: "select `player`.`id` AS `id`,CURRENT_TIMESTAMP AS `ts` from `player` where (`player`.`created_at` < CURRENT_TIMESTAMP)",
+        },
+        {
+            name: "nested",
+            expr: "select ifnull(concat(`game`.`player`.`name`,'@',`game`.`player`.`email`),'n/a') AS `x` from `game`.`player`",
+            want: "select ifnull((`player`.`name` || '@' || `player`.`email`), 'n/a') AS `x` from `player`",
+        },
+        {
+            name: "column named as database",
+            expr: "select `game`.`t`.`game` AS `game` from `game`.`t`",
+            want: "select `t`.`game` AS `game` from `t`",
+        },
+        {
+            name: "like escape",
+            expr: "select `t`.`id` AS `id` from `game`.`t` where (`t`.`name` like _utf8mb4'a\\_b%')",
+            want: "select `t`.`id` AS `id` from `t` where (`t`.`name` like 'a\\_b%' ESCAPE '\\')",
+        },
+        {
+            name: "not like escape",
+            expr: "select `t`.`id` AS `id` from `game`.`t` where (`t`.`name` not like '100\\%')",
+            want: "select `t`.`id` AS `id` from `t` where (`t`.`name` not like '100\\%' ESCAPE '\\')",
+        },
+        {
+            name: "like explicit escape",
+            expr: "select `t`.`id` AS `id` from `game`.`t` where (`t`.`name` like 'a\\_|_' escape '|')",
+            want: "select `t`.`id` AS `id` from `t` where (`t`.`name` like 'a\\_|_' escape '|')",
+        },
+        {
+            name: "like without backslash",
+            expr: "select `t`.`id` AS `id` from `game`.`t` where (`t`.`name` like 'a_b%')",
+            want: "select `t`.`id` AS `id` from `t` where (`t`.`name` like 'a_b%')",
+        },
+        {
+            name: "backslash outside like",
+            expr: "select concat(`t`.`name`,'\\_','\\n') AS `x` from `game`.`t`",
+            want: "select (`t`.`name` || '\\_' || '\n') AS `x` from `t`",
+        },
+        {
+            name: "length",
+            expr: "select length(`t`.`name`) AS `bytes`,char_length(`t`.`name`) AS `chars` from `game`.`t`",
+            want: "select length(CAST(`t`.`name` AS BLOB)) AS `bytes`,length(`t`.`name`) AS `chars` from `t`",
+        },
+        {
+            name:    "unsupported function",
+            expr:    "rand()",
+            wantErr: true,
+        },
+        {
+            name:    "variable",
+            expr:    "@a := 1",
+            wantErr: true,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := translateExpr(tt.expr, "game", "")
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("translateExpr() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if got != tt.want {
+                t.Errorf("translateExpr() = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestTranslateLike(t *testing.T) {
+    s, err := NewSQLiteWriter(":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer s.End()
+
+    tests := []struct {
+        expr  string
+        value string
+        match bool
+    }{
+        {expr: `? like 'a\_b'`, value: "a_b", match: true},
+        {expr: `? like 'a\_b'`, value: "axb", match: false},
+        {expr: `? like '100\%'`, value: "100%", match: true},
+        {expr: `? like '100\%'`, value: "1000", match: false},
+        {expr: `? like 'a\\\\b'`, value: `a\b`, match: true},
+        {expr: `? like 'a_b'`, value: "axb", match: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.expr+" "+tt.value, func(t *testing.T) {
+            expr, err := translateExpr(tt.expr, "game", "")
+            if err != nil {
+                t.Fatal(err)
+            }
+            var match bool
+            if err = s.db.Raw("SELECT "+expr, tt.value).Scan(&match).Error; err != nil {
+                t.Fatalf("%s: %v", expr, err)
+            }
+            if match != tt.match {
+                t.Errorf("%s = %v, want %v", expr, match, tt.match)
+            }
+        })
+    }
+}
+
+func TestTranslateLength(t *testing.T) {
+    s, err := NewSQLiteWriter(":memory:")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer s.End()
+
+    tests := []struct {
+        expr  string
+        value any
+        want  int64
+    }{
+        {expr: "length(?)", value: "张三", want: 6},
+        {expr: "char_length(?)", value: "张三", want: 2},
+        {expr: "octet_length(?)", value: "ab", want: 2},
+        {expr: "length(x'00FF02')", want: 3},
+        {expr: "length(?)", value: 12345, want: 5},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.expr, func(t *testing.T) {
+            expr, err := translateExpr(tt.expr, "game", "")
+            if err != nil {
+                t.Fatal(err)
+            }
+            var (
+                got  int64
+                args []any
+            )
+            if tt.value != nil {
+                args = append(args, tt.value)
+            }
+            if err = s.db.Raw("SELECT "+expr, args...).Scan(&got).Error; err != nil {
+                t.Fatalf("%s: %v", expr, err)
+            }
+            if got != tt.want {
+                t.Errorf("%s = %d, want %d", expr, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestIsJSONPathExpr(t *testing.T) {
+    tests := []struct {
+        expr string
+        want bool
+    }{
+        {expr: "json_extract(`doc`,_utf8mb4'$.name')", want: true},
+        {expr: "json_unquote(json_extract(`doc`,_utf8mb4'$.name'))", want: true},
+        {expr: "`doc`->'$.a'", want: true},
+        {expr: "`doc`->>'$.a'", want: true},
+        {expr: "json_unquote(`doc`)", want: false},
+        {expr: "concat(json_extract(`doc`,'$.a'),'x')", want: false},
+        {expr: "(json_extract(`doc`,'$.a') + 1)", want: false},
+        {expr: "`a` + `b`", want: false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.expr, func(t *testing.T) {
+            if got := isJSONPathExpr(tt.expr); got != tt.want {
+                t.Errorf("isJSONPathExpr() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
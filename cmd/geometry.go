@@ -0,0 +1,294 @@
+package cmd
+
+import (
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+)
+
+// WKB 几何类型。
+const (
+    wkbPoint              = 1
+    wkbLineString         = 2
+    wkbPolygon            = 3
+    wkbMultiPoint         = 4
+    wkbMultiLineString    = 5
+    wkbMultiPolygon       = 6
+    wkbGeometryCollection = 7
+
+    // ewkbSRID EWKB 类型中携带 SRID 的标志位。
+    ewkbSRID = 0x20000000
+)
+
+var wkbTypeNames = map[uint32]string{
+    wkbPoint:              "Point",
+    wkbLineString:         "LineString",
+    wkbPolygon:            "Polygon",
+    wkbMultiPoint:         "MultiPoint",
+    wkbMultiLineString:    "MultiLineString",
+    wkbMultiPolygon:       "MultiPolygon",
+    wkbGeometryCollection: "GeometryCollection",
+}
+
+// geometry WKB 解析结果。
+type geometry struct {
+    typ    uint32
+    points [][2]float64   // Point、LineString
+    rings  [][][2]float64 // Polygon
+    geoms  []*geometry    // Multi*、GeometryCollection
+}
+
+// isGeometryType 是否 MySQL 空间数据类型。
+func isGeometryType(dataType string) bool {
+    switch dataType {
+    case "GEOMETRY", "POINT", "LINESTRING", "POLYGON", "MULTIPOINT", "MULTILINESTRING", "MULTIPOLYGON", "GEOMETRYCOLLECTION", "GEOMCOLLECTION":
+        return true
+    }
+    return false
+}
+
+// convertGeometry MySQL 空间数据内部格式（4 字节小端 SRID + WKB）转换为指定格式，SRID 非 0 时保留:
+// wkt 输出 EWKT（SRID=4326;POINT(1 2)），wkb 输出 EWKB，geojson 输出 crs 成员。
+func convertGeometry(value []byte, format string) (any, error) {
+    if len(value) < 4 {
+        return nil, errors.New("空间数据长度不足")
+    }
+    srid := binary.LittleEndian.Uint32(value[:4])
+    wkb := value[4:]
+
+    if format == GeometryWKB {
+        if srid == 0 {
+            return wkb, nil
+        }
+        return toEWKB(wkb, srid)
+    }
+
+    g, n, err := parseWKB(wkb)
+    if err != nil {
+        return nil, err
+    }
+    if n != len(wkb) {
+        return nil, fmt.Errorf("WKB 末尾存在 %d 字节多余数据", len(wkb)-n)
+    }
+
+    if format == GeometryGeoJSON {
+        object := g.geoJSON()
+        if srid != 0 {
+            object["crs"] = map[string]any{
+                "type":       "name",
+                "properties": map[string]any{"name": fmt.Sprintf("EPSG:%d", srid)},
+            }
+        }
+        bs, err := json.Marshal(object)
+        if err != nil {
+            return nil, err
+        }
+        return string(bs), nil
+    }
+
+    if srid == 0 {
+        return g.wkt(), nil
+    }
+    return fmt.Sprintf("SRID=%d;%s", srid, g.wkt()), nil
+}
+
+// toEWKB WKB 类型设置 SRID 标志位并写入 SRID。
+func toEWKB(wkb []byte, srid uint32) ([]byte, error) {
+    if len(wkb) < 5 {
+        return nil, errors.New("WKB 长度不足")
+    }
+    order, err := getByteOrder(wkb[0])
+    if err != nil {
+        return nil, err
+    }
+
+    ewkb := make([]byte, 9, len(wkb)+4)
+    ewkb[0] = wkb[0]
+    order.PutUint32(ewkb[1:5], order.Uint32(wkb[1:5])|ewkbSRID)
+    order.PutUint32(ewkb[5:9], srid)
+    return append(ewkb, wkb[5:]...), nil
+}
+
+// getByteOrder WKB 字节序。
+func getByteOrder(b byte) (binary.ByteOrder, error) {
+    switch b {
+    case 0:
+        return binary.BigEndian, nil
+    case 1:
+        return binary.LittleEndian, nil
+    }
+    return nil, fmt.Errorf("WKB 字节序 %d 错误", b)
+}
+
+// parseWKB 解析 WKB，返回几何对象及读取的字节数。
+func parseWKB(b []byte) (*geometry, int, error) {
+    if len(b) < 5 {
+        return nil, 0, errors.New("WKB 长度不足")
+    }
+    order, err := getByteOrder(b[0])
+    if err != nil {
+        return nil, 0, err
+    }
+
+    g := &geometry{typ: order.Uint32(b[1:5])}
+    if _, ok := wkbTypeNames[g.typ]; !ok {
+        return nil, 0, fmt.Errorf("WKB 几何类型 %d 不支持", g.typ)
+    }
+
+    pos := 5
+    readUint32 := func() (uint32, error) {
+        if len(b) < pos+4 {
+            return 0, errors.New("WKB 长度不足")
+        }
+        v := order.Uint32(b[pos : pos+4])
+        pos += 4
+        return v, nil
+    }
+    readPoints := func(n uint32) ([][2]float64, error) {
+        if uint64(len(b)-pos) < uint64(n)*16 {
+            return nil, errors.New("WKB 长度不足")
+        }
+        points := make([][2]float64, n)
+        for i := range points {
+            points[i][0] = math.Float64frombits(order.Uint64(b[pos : pos+8]))
+            points[i][1] = math.Float64frombits(order.Uint64(b[pos+8 : pos+16]))
+            pos += 16
+        }
+        return points, nil
+    }
+
+    switch g.typ {
+    case wkbPoint:
+        if g.points, err = readPoints(1); err != nil {
+            return nil, 0, err
+        }
+        // POINT EMPTY 以 NaN 坐标表示。
+        if math.IsNaN(g.points[0][0]) && math.IsNaN(g.points[0][1]) {
+            g.points = nil
+        }
+    case wkbLineString:
+        n, err := readUint32()
+        if err != nil {
+            return nil, 0, err
+        }
+        if g.points, err = readPoints(n); err != nil {
+            return nil, 0, err
+        }
+    case wkbPolygon:
+        n, err := readUint32()
+        if err != nil {
+            return nil, 0, err
+        }
+        for i := uint32(0); i < n; i++ {
+            m, err := readUint32()
+            if err != nil {
+                return nil, 0, err
+            }
+            ring, err := readPoints(m)
+            if err != nil {
+                return nil, 0, err
+            }
+            g.rings = append(g.rings, ring)
+        }
+    default:
+        n, err := readUint32()
+        if err != nil {
+            return nil, 0, err
+        }
+        for i := uint32(0); i < n; i++ {
+            child, m, err := parseWKB(b[pos:])
+            if err != nil {
+                return nil, 0, err
+            }
+            pos += m
+            g.geoms = append(g.geoms, child)
+        }
+    }
+
+    return g, pos, nil
+}
+
+// wkt WKT 文本。
+func (g *geometry) wkt() string {
+    return strings.ToUpper(wkbTypeNames[g.typ]) + g.wktBody()
+}
+
+// wktBody WKT 坐标部分（不含类型名）。
+func (g *geometry) wktBody() string {
+    var parts []string
+
+    switch g.typ {
+    case wkbPoint, wkbLineString:
+        if len(g.points) == 0 {
+            return " EMPTY"
+        }
+        return "(" + wktPoints(g.points) + ")"
+    case wkbPolygon:
+        for _, ring := range g.rings {
+            parts = append(parts, "("+wktPoints(ring)+")")
+        }
+    case wkbGeometryCollection:
+        for _, child := range g.geoms {
+            parts = append(parts, child.wkt())
+        }
+    default:
+        // MULTIPOINT((1 2),(3 4))、MULTILINESTRING((...))、MULTIPOLYGON(((...)))
+        for _, child := range g.geoms {
+            parts = append(parts, child.wktBody())
+        }
+    }
+
+    if len(parts) == 0 {
+        return " EMPTY"
+    }
+    return "(" + strings.Join(parts, ",") + ")"
+}
+
+// wktPoints WKT 坐标序列: 1 2,3 4
+func wktPoints(points [][2]float64) string {
+    var ps []string
+    for _, point := range points {
+        ps = append(ps, strconv.FormatFloat(point[0], 'f', -1, 64)+" "+strconv.FormatFloat(point[1], 'f', -1, 64))
+    }
+    return strings.Join(ps, ",")
+}
+
+// geoJSON GeoJSON 几何对象。
+func (g *geometry) geoJSON() map[string]any {
+    object := map[string]any{"type": wkbTypeNames[g.typ]}
+    if g.typ == wkbGeometryCollection {
+        geometries := make([]any, 0, len(g.geoms))
+        for _, child := range g.geoms {
+            geometries = append(geometries, child.geoJSON())
+        }
+        object["geometries"] = geometries
+    } else {
+        object["coordinates"] = g.geoJSONCoordinates()
+    }
+    return object
+}
+
+// geoJSONCoordinates GeoJSON coordinates 成员。
+func (g *geometry) geoJSONCoordinates() any {
+    switch g.typ {
+    case wkbPoint:
+        if len(g.points) == 0 {
+            return []float64{}
+        }
+        return g.points[0]
+    case wkbLineString:
+        return append([][2]float64{}, g.points...)
+    case wkbPolygon:
+        return append([][][2]float64{}, g.rings...)
+    }
+
+    coordinates := make([]any, 0, len(g.geoms))
+    for _, child := range g.geoms {
+        coordinates = append(coordinates, child.geoJSONCoordinates())
+    }
+    return coordinates
+}
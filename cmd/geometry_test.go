@@ -0,0 +1,81 @@
+package cmd
+
+import (
+    "encoding/hex"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+// WKB 坐标（小端）: 1 2、3 4。
+const (
+    wkbLE12 = "000000000000f03f" + "0000000000000040"
+    wkbLE34 = "0000000000000840" + "0000000000001040"
+)
+
+func TestConvertGeometry(t *testing.T) {
+    tests := []struct {
+        name    string
+        value   string // 4 字节小端 SRID + WKB（十六进制）
+        format  string
+        want    any
+        wantErr bool
+    }{
+        {"wkt", "00000000" + "0101000000" + wkbLE12, GeometryWKT, "POINT(1 2)", false},
+        {"ewkt", "e6100000" + "0101000000" + wkbLE12, GeometryWKT, "SRID=4326;POINT(1 2)", false},
+        {"wkb", "00000000" + "0101000000" + wkbLE12, GeometryWKB, mustHex("0101000000" + wkbLE12), false},
+        {"ewkb", "e6100000" + "0101000000" + wkbLE12, GeometryWKB, mustHex("0101000020" + "e6100000" + wkbLE12), false},
+        {"ewkb big endian", "e6100000" + "0000000001" + "3ff0000000000000" + "4000000000000000", GeometryWKB,
+            mustHex("0020000001" + "000010e6" + "3ff0000000000000" + "4000000000000000"), false},
+        {"geojson", "00000000" + "0101000000" + wkbLE12, GeometryGeoJSON, `{"coordinates":[1,2],"type":"Point"}`, false},
+        {"geojson srid", "e6100000" + "0101000000" + wkbLE12, GeometryGeoJSON,
+            `{"coordinates":[1,2],"crs":{"properties":{"name":"EPSG:4326"},"type":"name"},"type":"Point"}`, false},
+        {"geojson big endian", "00000000" + "000000000200000002" + "3ff0000000000000" + "4000000000000000" + "4008000000000000" + "4010000000000000", GeometryGeoJSON,
+            `{"coordinates":[[1,2],[3,4]],"type":"LineString"}`, false},
+        {"ewkt multipoint", "110f0000" + "010400000002000000" + "0101000000" + wkbLE12 + "0101000000" + wkbLE34, GeometryWKT,
+            "SRID=3857;MULTIPOINT((1 2),(3 4))", false},
+        {"ewkt polygon", "e6100000" + "01030000000100000004000000" + wkbLE12 + wkbLE34 + "0000000000000840" + "0000000000000040" + wkbLE12, GeometryWKT,
+            "SRID=4326;POLYGON((1 2,3 4,3 2,1 2))", false},
+        {"empty collection", "00000000" + "010700000000000000", GeometryWKT, "GEOMETRYCOLLECTION EMPTY", false},
+        {"trailing bytes", "00000000" + "0101000000" + wkbLE12 + "00", GeometryWKT, nil, true},
+        {"short", "000000000101000000", GeometryWKT, nil, true},
+        {"byte order", "00000000" + "0201000000" + wkbLE12, GeometryGeoJSON, nil, true},
+        {"no srid", "000000", GeometryWKB, nil, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := convertGeometry(mustHex(tt.value), tt.format)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("convertGeometry() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("convertGeometry() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestParseWKB(t *testing.T) {
+    // 多余数据不计入读取的字节数，由调用方判断。
+    b := mustHex("0101000000" + wkbLE12 + "ffff")
+    g, n, err := parseWKB(b)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if n != len(b)-2 || g.wkt() != "POINT(1 2)" {
+        t.Errorf("parseWKB() = %s, %d, want POINT(1 2), %d", g.wkt(), n, len(b)-2)
+    }
+
+    if _, _, err = parseWKB(mustHex("0108000000")); err == nil || !strings.Contains(err.Error(), "8") {
+        t.Errorf("parseWKB() error = %v, want unsupported type 8", err)
+    }
+}
+
+func mustHex(s string) []byte {
+    b, err := hex.DecodeString(s)
+    if err != nil {
+        panic(err)
+    }
+    return b
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "fmt"
+    "strconv"
+
+    "github.com/asaskevich/govalidator"
+)
+
+var (
+    maskSurnames   = []rune("张王李赵刘陈杨黄周吴徐孙马朱胡郭何林高罗")
+    maskGivenNames = []rune("伟芳娜敏静磊洋勇艳杰涛明超秀兰霞平刚桂英华玉萍红娟建军")
+)
+
+// maskValue 按字段脱敏规则转换 SQLite 字段值，NULL 保持不变。
+// hash、email、name、phone、token 由 salt 与原值的 HMAC-SHA256 确定，相同原值在各表得到相同结果，关联查询仍然有效。
+func maskValue(col *MySQL2SQLiteColumn, value any) any {
+    if col.Mask == nil || value == nil {
+        return value
+    }
+
+    switch col.Mask.Rule {
+    case MaskFixed:
+        return col.Mask.Value
+    case MaskNull:
+        return nil
+    case MaskTruncate:
+        if bs, ok := value.([]byte); ok {
+            if len(bs) > col.Mask.Length {
+                return bs[:col.Mask.Length]
+            }
+            return bs
+        }
+        rs := []rune(getMaskInput(value))
+        if len(rs) > col.Mask.Length {
+            rs = rs[:col.Mask.Length]
+        }
+        return string(rs)
+    }
+
+    sum := getMaskSum(value)
+    switch col.Mask.Rule {
+    case MaskHash:
+        return hex.EncodeToString(sum)
+    case MaskEmail:
+        return fmt.Sprintf("user_%s@example.com", hex.EncodeToString(sum[:5]))
+    case MaskName:
+        name := string(maskSurnames[int(sum[0])%len(maskSurnames)]) + string(maskGivenNames[int(sum[1])%len(maskGivenNames)])
+        if sum[2]%2 == 0 {
+            name += string(maskGivenNames[int(sum[3])%len(maskGivenNames)])
+        }
+        return name
+    case MaskPhone:
+        return fmt.Sprintf("1%d%09d", 3+sum[0]%7, binary.BigEndian.Uint64(sum[1:9])%1000000000)
+    case MaskToken:
+        // INTEGER 字段保持整数（63 位正整数），可继续作为主键、外键关联。
+        if col.SQLiteDataType == "INTEGER" {
+            return int64(binary.BigEndian.Uint64(sum[:8]) >> 1)
+        }
+        return "tk_" + hex.EncodeToString(sum[:8])
+    }
+    return value
+}
+
+// isIntegerMask 脱敏规则对整数原值的结果是否仍为整数。(phone、truncate 结果为数字文本)
+func isIntegerMask(rule *MaskRule) bool {
+    switch rule.Rule {
+    case MaskToken, MaskPhone, MaskTruncate:
+        return true
+    case MaskFixed:
+        _, err := strconv.ParseInt(rule.Value, 10, 64)
+        return err == nil
+    }
+    return false
+}
+
+// getMaskSum 原值的 HMAC-SHA256。
+func getMaskSum(value any) []byte {
+    mac := hmac.New(sha256.New, []byte(maskSalt))
+    mac.Write([]byte(getMaskInput(value)))
+    return mac.Sum(nil)
+}
+
+// getMaskInput 原值的文本形式，不同整数类型的相同值结果一致。
+func getMaskInput(value any) string {
+    if bs, ok := value.([]byte); ok {
+        return string(bs)
+    }
+    return govalidator.ToString(value)
+}
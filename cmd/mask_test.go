@@ -0,0 +1,118 @@
+package cmd
+
+import (
+    "bytes"
+    "regexp"
+    "testing"
+)
+
+func TestMaskValue(t *testing.T) {
+    defer func(salt string) {
+        maskSalt = salt
+    }(maskSalt)
+    maskSalt = "s3cret"
+
+    text := &MySQL2SQLiteColumn{SQLiteDataType: "TEXT"}
+    integer := &MySQL2SQLiteColumn{SQLiteDataType: "INTEGER"}
+
+    tests := []struct {
+        name    string
+        col     *MySQL2SQLiteColumn
+        rule    *MaskRule
+        value   any
+        want    any
+        pattern string
+    }{
+        {name: "no rule", col: text, value: "a", want: "a"},
+        {name: "nil", col: text, rule: &MaskRule{Rule: MaskHash}, value: nil, want: nil},
+        {name: "fixed", col: text, rule: &MaskRule{Rule: MaskFixed, Value: "***"}, value: "a", want: "***"},
+        {name: "null", col: text, rule: &MaskRule{Rule: MaskNull}, value: "a", want: nil},
+        {name: "truncate", col: text, rule: &MaskRule{Rule: MaskTruncate, Length: 2}, value: "张三丰", want: "张三"},
+        {name: "truncate short", col: text, rule: &MaskRule{Rule: MaskTruncate, Length: 5}, value: "ab", want: "ab"},
+        {name: "truncate bytes", col: &MySQL2SQLiteColumn{SQLiteDataType: "BLOB"}, rule: &MaskRule{Rule: MaskTruncate, Length: 1}, value: []byte{1, 2}, want: []byte{1}},
+        {name: "hash", col: text, rule: &MaskRule{Rule: MaskHash}, value: "a", pattern: `^[0-9a-f]{64}$`},
+        {name: "email", col: text, rule: &MaskRule{Rule: MaskEmail}, value: "a@b.com", pattern: `^user_[0-9a-f]{10}@example\.com$`},
+        {name: "name", col: text, rule: &MaskRule{Rule: MaskName}, value: "张三", pattern: `^\p{Han}{2,3}$`},
+        {name: "phone", col: text, rule: &MaskRule{Rule: MaskPhone}, value: "13800138000", pattern: `^1[3-9][0-9]{9}$`},
+        {name: "token", col: text, rule: &MaskRule{Rule: MaskToken}, value: int64(42), pattern: `^tk_[0-9a-f]{16}$`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            col := *tt.col
+            col.Mask = tt.rule
+            got := maskValue(&col, tt.value)
+            if tt.pattern != "" {
+                if s, ok := got.(string); !ok || !regexp.MustCompile(tt.pattern).MatchString(s) {
+                    t.Errorf("maskValue() = %v, want match %s", got, tt.pattern)
+                }
+                return
+            }
+            if bs, ok := tt.want.([]byte); ok {
+                if !bytes.Equal(got.([]byte), bs) {
+                    t.Errorf("maskValue() = %v, want %v", got, tt.want)
+                }
+                return
+            }
+            if got != tt.want {
+                t.Errorf("maskValue() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+
+    // 相同原值在各表得到相同结果，不同整数类型的相同值一致。
+    token := &MySQL2SQLiteColumn{SQLiteDataType: "INTEGER", Mask: &MaskRule{Rule: MaskToken}}
+    id, ok := maskValue(token, int64(42)).(int64)
+    if !ok || id <= 0 {
+        t.Fatalf("INTEGER token = %v, want positive int64", id)
+    }
+    if other := maskValue(token, uint32(42)); other != id {
+        t.Errorf("token(uint32) = %v, want %v", other, id)
+    }
+    if other := maskValue(token, int64(43)); other == id {
+        t.Errorf("token(43) = token(42) = %v", id)
+    }
+
+    integer.Mask = &MaskRule{Rule: MaskHash}
+    hash := maskValue(integer, int64(42))
+    maskSalt = "other"
+    if maskValue(integer, int64(42)) == hash {
+        t.Error("salt 不同时结果应不同")
+    }
+}
+
+func TestCheckRowidMask(t *testing.T) {
+    tests := []struct {
+        name     string
+        dataType string
+        keys     []string
+        rule     *MaskRule
+        note     bool
+    }{
+        {name: "hash", dataType: "INTEGER", keys: []string{"id"}, rule: &MaskRule{Rule: MaskHash}, note: true},
+        {name: "email", dataType: "INTEGER", keys: []string{"id"}, rule: &MaskRule{Rule: MaskEmail}, note: true},
+        {name: "name", dataType: "INTEGER", keys: []string{"id"}, rule: &MaskRule{Rule: MaskName}, note: true},
+        {name: "null", dataType: "INTEGER", keys: []string{"id"}, rule: &MaskRule{Rule: MaskNull}, note: true},
+        {name: "fixed text", dataType: "INTEGER", keys: []string{"id"}, rule: &MaskRule{Rule: MaskFixed, Value: "***"}, note: true},
+        {name: "fixed integer", dataType: "INTEGER", keys: []string{"id"}, rule: &MaskRule{Rule: MaskFixed, Value: "0"}},
+        {name: "token", dataType: "INTEGER", keys: []string{"id"}, rule: &MaskRule{Rule: MaskToken}},
+        {name: "phone", dataType: "INTEGER", keys: []string{"id"}, rule: &MaskRule{Rule: MaskPhone}},
+        {name: "text key", dataType: "TEXT", keys: []string{"id"}, rule: &MaskRule{Rule: MaskHash}},
+        {name: "composite key", dataType: "INTEGER", keys: []string{"id", "type"}, rule: &MaskRule{Rule: MaskHash}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := &Converter{
+                serverTable:        &Table{TableName: "t"},
+                serverTableColumns: []*MySQL2SQLiteColumn{{ColumnName: "id", SQLiteDataType: tt.dataType, Mask: tt.rule}},
+                serverTableKeys:    tt.keys,
+                plan:               &TablePlan{},
+            }
+            c.checkRowidMask()
+            if note := len(c.plan.Notes) > 0; note != tt.note {
+                t.Errorf("checkRowidMask() notes = %q, want note %v", c.plan.Notes, tt.note)
+            }
+        })
+    }
+}
@@ -85,6 +85,7 @@ type Statistic struct {
     COMMENT      sql.NullString `gorm:"column:COMMENT"`
     IndexComment string         `gorm:"column:INDEX_COMMENT"`
     IsVisible    sql.NullString `gorm:"column:IS_VISIBLE"`
+    EXPRESSION   sql.NullString `gorm:"column:EXPRESSION"`
 }
 
 type View struct {
@@ -137,3 +138,13 @@ type KeyColumnUsage struct {
     ReferencedTableName        string `gorm:"column:REFERENCED_TABLE_NAME"`
     ReferencedColumnName       string `gorm:"column:REFERENCED_COLUMN_NAME"`
 }
+
+type Trigger struct {
+    TriggerCatalog    string `gorm:"column:TRIGGER_CATALOG"`
+    TriggerSchema     string `gorm:"column:TRIGGER_SCHEMA"`
+    TriggerName       string `gorm:"column:TRIGGER_NAME"`
+    EventManipulation string `gorm:"column:EVENT_MANIPULATION"`
+    EventObjectSchema string `gorm:"column:EVENT_OBJECT_SCHEMA"`
+    EventObjectTable  string `gorm:"column:EVENT_OBJECT_TABLE"`
+    ActionTiming      string `gorm:"column:ACTION_TIMING"`
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+    "fmt"
+    "path"
+    "regexp"
+    "strings"
+
+    "github.com/camry/g/gutil"
+)
+
+// Pattern 表、字段匹配规则: <表>[.<字段>]，各部分为通配符（* ? [...]）或 /正则/。
+// 如 log_2024_*、*.*_tmp、/^tmp_\d+$/、user./^secret_/。
+type Pattern struct {
+    table  *namePattern
+    column *namePattern // nil 表示整表
+}
+
+// namePattern 表名或字段名匹配规则。
+type namePattern struct {
+    glob string
+    re   *regexp.Regexp
+}
+
+// ParsePattern 解析匹配规则。
+func ParsePattern(s string) (*Pattern, error) {
+    var tablePart, columnPart string
+    hasColumn := false
+    if strings.HasPrefix(s, "/") {
+        end := strings.Index(s[1:], "/")
+        if end < 0 {
+            return nil, fmt.Errorf("匹配规则 `%s` 正则缺少结尾 /。", s)
+        }
+        tablePart, columnPart = s[:end+2], s[end+2:]
+        if columnPart != "" {
+            if !strings.HasPrefix(columnPart, ".") {
+                return nil, fmt.Errorf("匹配规则 `%s` 格式错误。(格式: <表>[.<字段>])", s)
+            }
+            columnPart, hasColumn = columnPart[1:], true
+        }
+    } else {
+        tablePart, columnPart, hasColumn = strings.Cut(s, ".")
+    }
+
+    p := &Pattern{}
+    var err error
+    if p.table, err = parseNamePattern(tablePart); err != nil {
+        return nil, fmt.Errorf("匹配规则 `%s` 错误: %w", s, err)
+    }
+    if hasColumn {
+        if p.column, err = parseNamePattern(columnPart); err != nil {
+            return nil, fmt.Errorf("匹配规则 `%s` 错误: %w", s, err)
+        }
+    }
+    return p, nil
+}
+
+// parseNamePattern 解析通配符或 /正则/。
+func parseNamePattern(s string) (*namePattern, error) {
+    if s == "" {
+        return nil, fmt.Errorf("名称为空")
+    }
+    if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+        re, err := regexp.Compile(s[1 : len(s)-1])
+        if err != nil {
+            return nil, err
+        }
+        return &namePattern{re: re}, nil
+    }
+    if _, err := path.Match(s, ""); err != nil {
+        return nil, err
+    }
+    return &namePattern{glob: s}, nil
+}
+
+// match 名称是否匹配，正则按 regexp.MatchString 部分匹配。
+func (p *namePattern) match(name string) bool {
+    if p.re != nil {
+        return p.re.MatchString(name)
+    }
+    ok, _ := path.Match(p.glob, name)
+    return ok
+}
+
+// parsePatterns 解析一组匹配规则并追加到 patterns。
+func parsePatterns(patterns []*Pattern, ss []string) ([]*Pattern, error) {
+    for _, s := range ss {
+        p, err := ParsePattern(s)
+        if err != nil {
+            return nil, err
+        }
+        patterns = append(patterns, p)
+    }
+    return patterns, nil
+}
+
+// isIgnoreTable 整表是否忽略: ignores 整表忽略或匹配 exclude 整表规则，
+// 指定了 include 时表须匹配其中一条（字段规则按表部分匹配）。exclude 优先于 include。
+func isIgnoreTable(tableName string) bool {
+    if v, ok := icMap[tableName]; ok && len(v.Columns) == 0 {
+        return true
+    }
+    for _, p := range excludePatterns {
+        if p.column == nil && p.table.match(tableName) {
+            return true
+        }
+    }
+    if len(includePatterns) == 0 {
+        return false
+    }
+    for _, p := range includePatterns {
+        if p.table.match(tableName) {
+            return false
+        }
+    }
+    return true
+}
+
+// isIgnoreColumn 字段是否忽略: ignores 字段或匹配 exclude 字段规则，
+// 表匹配了 include 字段规则时字段须匹配其中一条。exclude 优先于 include。
+func isIgnoreColumn(tableName, columnName string) bool {
+    if v, ok := icMap[tableName]; ok && gutil.InArray(columnName, v.Columns) {
+        return true
+    }
+    for _, p := range excludePatterns {
+        if p.column != nil && p.table.match(tableName) && p.column.match(columnName) {
+            return true
+        }
+    }
+
+    included := true
+    for _, p := range includePatterns {
+        if p.column == nil || !p.table.match(tableName) {
+            continue
+        }
+        if p.column.match(columnName) {
+            return false
+        }
+        included = false
+    }
+    return !included
+}
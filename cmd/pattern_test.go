@@ -0,0 +1,108 @@
+package cmd
+
+import "testing"
+
+func TestParsePattern(t *testing.T) {
+    tests := []struct {
+        pattern string
+        table   string
+        column  string // 空表示整表规则
+        match   bool
+        wantErr bool
+    }{
+        {pattern: "log_2024_*", table: "log_2024_01", match: true},
+        {pattern: "log_2024_*", table: "log_2023_01", match: false},
+        {pattern: "*.*_tmp", table: "user", column: "name_tmp", match: true},
+        {pattern: "*.*_tmp", table: "user", column: "name", match: false},
+        {pattern: "/^tmp_\\d+$/", table: "tmp_12", match: true},
+        {pattern: "/^tmp_\\d+$/", table: "tmp_x", match: false},
+        {pattern: "/^a\\.b$/", table: "a.b", match: true},
+        {pattern: "user./^secret_/", table: "user", column: "secret_key", match: true},
+        {pattern: "user./^secret_/", table: "user", column: "key", match: false},
+        {pattern: "/^user$/./_at$/", table: "user", column: "created_at", match: true},
+        {pattern: "/^user", wantErr: true},
+        {pattern: "/^user/x", wantErr: true},
+        {pattern: "/(/", wantErr: true},
+        {pattern: "[", wantErr: true},
+        {pattern: "user.", wantErr: true},
+        {pattern: "", wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.pattern, func(t *testing.T) {
+            p, err := ParsePattern(tt.pattern)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ParsePattern() error = %v, wantErr %v", err, tt.wantErr)
+            }
+            if err != nil {
+                return
+            }
+            if (p.column == nil) != (tt.column == "") {
+                t.Fatalf("ParsePattern() column = %v, want column %q", p.column, tt.column)
+            }
+            match := p.table.match(tt.table)
+            if p.column != nil {
+                match = match && p.column.match(tt.column)
+            }
+            if match != tt.match {
+                t.Errorf("match(%q, %q) = %v, want %v", tt.table, tt.column, match, tt.match)
+            }
+        })
+    }
+}
+
+func TestIsIgnore(t *testing.T) {
+    defer func(includes, excludes []*Pattern, ic map[string]*IgnoreTable) {
+        includePatterns, excludePatterns, icMap = includes, excludes, ic
+    }(includePatterns, excludePatterns, icMap)
+
+    var err error
+    includePatterns, err = parsePatterns(nil, []string{"base_*", "user.id", "user./^name/"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    excludePatterns, err = parsePatterns(nil, []string{"base_tmp", "*.remark", "user.name_tmp"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    icMap = map[string]*IgnoreTable{
+        "base_log":  {Table: "base_log"},
+        "base_item": {Table: "base_item", Columns: []string{"secret"}},
+    }
+
+    tables := []struct {
+        table  string
+        ignore bool
+    }{
+        {"base_item", false},
+        {"base_tmp", true}, // exclude 优先于 include
+        {"base_log", true}, // ignores 整表忽略
+        {"user", false},    // 匹配 include 字段规则的表
+        {"order", true},    // 未匹配 include
+    }
+    for _, tt := range tables {
+        if got := isIgnoreTable(tt.table); got != tt.ignore {
+            t.Errorf("isIgnoreTable(%q) = %v, want %v", tt.table, got, tt.ignore)
+        }
+    }
+
+    columns := []struct {
+        table  string
+        column string
+        ignore bool
+    }{
+        {"base_item", "name", false},
+        {"base_item", "secret", true}, // ignores 字段
+        {"base_item", "remark", true}, // exclude 字段规则
+        {"user", "id", false},
+        {"user", "name", false},
+        {"user", "nickname", true}, // 表匹配了 include 字段规则，字段未匹配
+        {"user", "name_tmp", true}, // exclude 优先于 include
+        {"user", "remark", true},   // exclude 优先于 include
+    }
+    for _, tt := range columns {
+        if got := isIgnoreColumn(tt.table, tt.column); got != tt.ignore {
+            t.Errorf("isIgnoreColumn(%q, %q) = %v, want %v", tt.table, tt.column, got, tt.ignore)
+        }
+    }
+}
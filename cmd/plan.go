@@ -0,0 +1,217 @@
+package cmd
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "text/tabwriter"
+
+    "github.com/camry/g/glog"
+    "github.com/spf13/cobra"
+    "gorm.io/gorm"
+)
+
+var planCmd = &cobra.Command{
+    Use:   "plan",
+    Short: "Print the MySQL to SQLite3 conversion plan without dumping data.",
+    Long:  "读取表结构并输出各表转换报告: 字段类型转换、忽略的字段、不转换的索引、触发器、视图、生成列、空间数据及预计行数，不读取数据。(同 --dry-run)",
+    Run: func(cmd *cobra.Command, args []string) {
+        checkFlags()
+        serverDbConfig, serverDb, serverTableData := openServerDb()
+        loadConfig()
+        cobra.CheckErr(renameTables(serverTableData))
+        cobra.CheckErr(plan(os.Stdout, serverDbConfig, serverDb, serverTableData))
+    },
+}
+
+func init() {
+    rootCmd.AddCommand(planCmd)
+}
+
+// TablePlan 单表转换报告。
+type TablePlan struct {
+    Table    *Table
+    Ignored  bool
+    Columns  []*ColumnPlan
+    Indexes  []*IndexPlan
+    Triggers []string
+    Notes    []string
+}
+
+// ColumnPlan 字段转换报告，忽略的字段 SQLiteDataType 为空。
+type ColumnPlan struct {
+    ColumnName       string
+    ColumnType       string
+    SQLiteColumnName string
+    SQLiteDataType   string
+    Note             string
+}
+
+// IndexPlan 索引转换报告。
+type IndexPlan struct {
+    IndexName string
+    Columns   []string
+    Result    string
+}
+
+// plan 按 Converter.create 解析表结构，输出转换报告。
+func plan(w io.Writer, serverDbConfig *DbConfig, serverDb *gorm.DB, serverTableData []*Table) error {
+    var serverTriggerData []Trigger
+    serverDb.Table("TRIGGERS").Order("`TRIGGER_NAME` ASC").Find(
+        &serverTriggerData,
+        "`EVENT_OBJECT_SCHEMA` = ?", serverDbConfig.Database,
+    )
+
+    if err := loadIndexTables(serverDbConfig, serverDb, serverTableData); err != nil {
+        return err
+    }
+
+    var tablePlans []*TablePlan
+    for _, serverTable := range serverTableData {
+        tablePlan := &TablePlan{Table: serverTable}
+        tablePlans = append(tablePlans, tablePlan)
+        for _, serverTrigger := range serverTriggerData {
+            if serverTrigger.EventObjectTable == serverTable.TableName {
+                tablePlan.Triggers = append(tablePlan.Triggers, fmt.Sprintf("`%s` %s %s", serverTrigger.TriggerName, serverTrigger.ActionTiming, serverTrigger.EventManipulation))
+            }
+        }
+
+        ignoreTable := getIgnoreTable(serverTable.TableName)
+        if ignoreTable == nil {
+            tablePlan.Ignored = true
+            continue
+        }
+
+        if filterTable, ok := filterMap[serverTable.TableName]; ok && serverTable.TableType == "BASE TABLE" {
+            tablePlan.Notes = append(tablePlan.Notes, getFilterNote(filterTable))
+        }
+
+        converter := NewConverter(serverDbConfig, serverDb, serverTable, ignoreTable, discardWriter{})
+        converter.plan = tablePlan
+        switch serverTable.TableType {
+        case "BASE TABLE":
+            converter.create()
+        case "VIEW":
+            converter.createView()
+        }
+    }
+
+    return writePlan(w, tablePlans)
+}
+
+// writePlan 输出转换报告。
+func writePlan(w io.Writer, tablePlans []*TablePlan) error {
+    var tables, views, ignored int
+    var rows int64
+
+    tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+    for _, tablePlan := range tablePlans {
+        serverTable := tablePlan.Table
+        tableName := serverTable.TableName
+        if sqliteTableName := renameTable(tableName); sqliteTableName != tableName && !tablePlan.Ignored {
+            tableName = fmt.Sprintf("%s` → `%s", tableName, sqliteTableName)
+        }
+        switch {
+        case tablePlan.Ignored:
+            ignored++
+            fmt.Fprintf(tw, "表 `%s`: 忽略\n\n", serverTable.TableName)
+            continue
+        case serverTable.TableType == "VIEW":
+            views++
+            fmt.Fprintf(tw, "视图 `%s`\n", tableName)
+        default:
+            tables++
+            rows += serverTable.TableRows.Int64
+            fmt.Fprintf(tw, "表 `%s`: 预计 %d 行\n", tableName, serverTable.TableRows.Int64)
+        }
+
+        for _, column := range tablePlan.Columns {
+            sqliteDataType := column.SQLiteDataType
+            if sqliteDataType == "" {
+                sqliteDataType = "-"
+            } else if column.SQLiteColumnName != column.ColumnName {
+                sqliteDataType = fmt.Sprintf("`%s` %s", column.SQLiteColumnName, sqliteDataType)
+            }
+            note := ""
+            if column.Note != "" {
+                note = "\t" + column.Note
+            }
+            fmt.Fprintf(tw, "  `%s`\t%s\t→ %s%s\n", column.ColumnName, column.ColumnType, sqliteDataType, note)
+        }
+        for _, index := range tablePlan.Indexes {
+            fmt.Fprintf(tw, "  索引 `%s`\t(%s)\t→ %s\n", index.IndexName, strings.Join(index.Columns, ","), index.Result)
+        }
+        for _, trigger := range tablePlan.Triggers {
+            fmt.Fprintf(tw, "  触发器 %s\t\t→ 不转换\n", trigger)
+        }
+        for _, note := range tablePlan.Notes {
+            fmt.Fprintf(tw, "  注意: %s\n", note)
+        }
+        fmt.Fprintln(tw)
+    }
+    fmt.Fprintf(tw, "共 %d 个表（预计 %d 行）、%d 个视图，忽略 %d 个。\n", tables, rows, views, ignored)
+
+    return tw.Flush()
+}
+
+// getFilterNote 过滤条件说明: 只导出 WHERE ... ORDER BY ... LIMIT n
+func getFilterNote(filterTable *FilterTable) string {
+    var ss []string
+    if filterTable.Where != "" {
+        ss = append(ss, "WHERE "+filterTable.Where)
+    }
+    if filterTable.OrderBy != "" {
+        ss = append(ss, "ORDER BY "+filterTable.OrderBy)
+    }
+    if filterTable.Limit > 0 {
+        ss = append(ss, fmt.Sprintf("LIMIT %d", filterTable.Limit))
+    }
+    return "只导出 " + strings.Join(ss, " ")
+}
+
+// planColumn 记录字段转换，column 为 nil 表示已忽略。
+func (c *Converter) planColumn(serverColumn Column, column *MySQL2SQLiteColumn) {
+    if c.plan == nil {
+        return
+    }
+
+    columnPlan := &ColumnPlan{ColumnName: serverColumn.ColumnName, ColumnType: serverColumn.ColumnType}
+    c.plan.Columns = append(c.plan.Columns, columnPlan)
+    if column == nil {
+        columnPlan.Note = "忽略"
+        return
+    }
+
+    columnPlan.SQLiteColumnName, columnPlan.SQLiteDataType = column.SQLiteColumnName, column.SQLiteDataType
+    extra := strings.ToUpper(serverColumn.EXTRA)
+    switch {
+    case column.Generated:
+        columnPlan.Note = "生成列"
+    case strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED"):
+        columnPlan.Note = "生成列，按普通字段导出"
+    case column.Mask != nil:
+        columnPlan.Note = fmt.Sprintf("脱敏 %s", column.Mask.Rule)
+    case column.GeometryFormat != "":
+        columnPlan.Note = fmt.Sprintf("空间数据 %s", column.GeometryFormat)
+    case column.DataType == "DECIMAL" && column.SQLiteDataType == "INTEGER":
+        columnPlan.Note = fmt.Sprintf("放大 10^%d", column.NumericScale)
+    }
+}
+
+// planIndex 记录索引转换。
+func (c *Converter) planIndex(indexName string, statisticMap map[int]Statistic, result string) {
+    if c.plan == nil {
+        return
+    }
+    c.plan.Indexes = append(c.plan.Indexes, &IndexPlan{IndexName: indexName, Columns: c.getIndexColumns(statisticMap), Result: result})
+}
+
+// warnf 转换警告，输出报告时记入报告。
+func (c *Converter) warnf(format string, a ...any) {
+    if c.plan != nil {
+        c.plan.Notes = append(c.plan.Notes, fmt.Sprintf(format, a...))
+        return
+    }
+    glog.Warnf(format, a...)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+    "bytes"
+    "database/sql"
+    "reflect"
+    "testing"
+)
+
+func TestWritePlan(t *testing.T) {
+    tablePlans := []*TablePlan{
+        {
+            Table: &Table{TableName: "player", TableType: "BASE TABLE", TableRows: sql.NullInt64{Int64: 120, Valid: true}},
+            Columns: []*ColumnPlan{
+                {ColumnName: "id", ColumnType: "bigint unsigned", SQLiteColumnName: "id", SQLiteDataType: "INTEGER"},
+                {ColumnName: "gold", ColumnType: "decimal(10,2)", SQLiteColumnName: "gold_cent", SQLiteDataType: "INTEGER", Note: "放大 10^2"},
+                {ColumnName: "secret", ColumnType: "varchar(32)", Note: "忽略"},
+            },
+            Indexes:  []*IndexPlan{{IndexName: "idx_name", Columns: []string{"name", "id"}, Result: "CREATE INDEX"}},
+            Triggers: []string{"`trg` BEFORE INSERT"},
+            Notes:    []string{"字段 `x` 默认值无法转换"},
+        },
+        {Table: &Table{TableName: "log", TableType: "BASE TABLE"}, Ignored: true},
+        {
+            Table:   &Table{TableName: "v_player", TableType: "VIEW"},
+            Columns: []*ColumnPlan{{ColumnName: "id", ColumnType: "bigint unsigned", SQLiteColumnName: "id", SQLiteDataType: "INTEGER"}},
+        },
+        {Table: &Table{TableName: "item", TableType: "BASE TABLE", TableRows: sql.NullInt64{Int64: 30, Valid: true}}},
+    }
+
+    want := "表 `player`: 预计 120 行\n" +
+        "  `id`                     bigint unsigned  → INTEGER\n" +
+        "  `gold`                   decimal(10,2)    → `gold_cent` INTEGER  放大 10^2\n" +
+        "  `secret`                 varchar(32)      → -                    忽略\n" +
+        "  索引 `idx_name`            (name,id)        → CREATE INDEX\n" +
+        "  触发器 `trg` BEFORE INSERT                   → 不转换\n" +
+        "  注意: 字段 `x` 默认值无法转换\n" +
+        "\n" +
+        "表 `log`: 忽略\n" +
+        "\n" +
+        "视图 `v_player`\n" +
+        "  `id`  bigint unsigned  → INTEGER\n" +
+        "\n" +
+        "表 `item`: 预计 30 行\n" +
+        "\n" +
+        "共 2 个表（预计 150 行）、1 个视图，忽略 1 个。\n"
+
+    var buf bytes.Buffer
+    if err := writePlan(&buf, tablePlans); err != nil {
+        t.Fatal(err)
+    }
+    if got := buf.String(); got != want {
+        t.Errorf("writePlan() =\n%s\nwant\n%s", got, want)
+    }
+}
+
+func TestPlanColumn(t *testing.T) {
+    tests := []struct {
+        name   string
+        extra  string
+        column *MySQL2SQLiteColumn
+        want   ColumnPlan
+    }{
+        {
+            name: "ignored",
+            want: ColumnPlan{ColumnName: "c", ColumnType: "t", Note: "忽略"},
+        },
+        {
+            name:   "plain",
+            column: &MySQL2SQLiteColumn{DataType: "VARCHAR", SQLiteDataType: "TEXT"},
+            want:   ColumnPlan{ColumnName: "c", ColumnType: "t", SQLiteDataType: "TEXT"},
+        },
+        {
+            name:   "generated",
+            extra:  "VIRTUAL GENERATED",
+            column: &MySQL2SQLiteColumn{DataType: "INT", SQLiteDataType: "INTEGER", Generated: true},
+            want:   ColumnPlan{ColumnName: "c", ColumnType: "t", SQLiteDataType: "INTEGER", Note: "生成列"},
+        },
+        {
+            name:   "generated as plain column",
+            extra:  "STORED GENERATED",
+            column: &MySQL2SQLiteColumn{DataType: "INT", SQLiteDataType: "INTEGER"},
+            want:   ColumnPlan{ColumnName: "c", ColumnType: "t", SQLiteDataType: "INTEGER", Note: "生成列，按普通字段导出"},
+        },
+        {
+            name:   "geometry",
+            column: &MySQL2SQLiteColumn{DataType: "POINT", SQLiteDataType: "TEXT", GeometryFormat: GeometryWKT},
+            want:   ColumnPlan{ColumnName: "c", ColumnType: "t", SQLiteDataType: "TEXT", Note: "空间数据 " + GeometryWKT},
+        },
+        {
+            name:   "scaled decimal",
+            column: &MySQL2SQLiteColumn{DataType: "DECIMAL", SQLiteDataType: "INTEGER", NumericScale: 2},
+            want:   ColumnPlan{ColumnName: "c", ColumnType: "t", SQLiteDataType: "INTEGER", Note: "放大 10^2"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := &Converter{plan: &TablePlan{}}
+            c.planColumn(Column{ColumnName: "c", ColumnType: "t", EXTRA: tt.extra}, tt.column)
+            if len(c.plan.Columns) != 1 || *c.plan.Columns[0] != tt.want {
+                t.Errorf("planColumn() = %+v, want %+v", c.plan.Columns, tt.want)
+            }
+        })
+    }
+}
+
+func TestGetFilterNote(t *testing.T) {
+    tests := []struct {
+        filterTable *FilterTable
+        want        string
+    }{
+        {&FilterTable{Where: "`id` > 10"}, "只导出 WHERE `id` > 10"},
+        {&FilterTable{OrderBy: "`id` DESC", Limit: 100}, "只导出 ORDER BY `id` DESC LIMIT 100"},
+        {&FilterTable{Where: "`status` = 1", OrderBy: "`id`", Limit: 5}, "只导出 WHERE `status` = 1 ORDER BY `id` LIMIT 5"},
+        {&FilterTable{Limit: 1}, "只导出 LIMIT 1"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.want, func(t *testing.T) {
+            if got := getFilterNote(tt.filterTable); got != tt.want {
+                t.Errorf("getFilterNote() = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestPlanIgnoredKeyColumn(t *testing.T) {
+    defer func(m map[string]*IgnoreTable) {
+        icMap = m
+    }(icMap)
+    icMap = map[string]*IgnoreTable{"t": {Table: "t", Columns: []string{"code"}}}
+
+    c := &Converter{serverTable: &Table{TableName: "t"}, sqliteTableName: "t", plan: &TablePlan{}}
+    statisticMap := map[int]Statistic{
+        1: {IndexName: "uk_code", SeqInIndex: 1, ColumnName: "code"},
+    }
+
+    if got, want := c.getPrimaryKey(statisticMap), "PRIMARY KEY (`code`)"; got != want {
+        t.Errorf("getPrimaryKey() = %q, want %q", got, want)
+    }
+    if got, want := c.createUniqueKey("uk_code", statisticMap), "CREATE UNIQUE INDEX `uk_code` ON `t` (`code`);"; got != want {
+        t.Errorf("createUniqueKey() = %q, want %q", got, want)
+    }
+    want := []string{
+        "表 `t` 主键字段 `code` 不能忽略，转换时将终止。",
+        "表 `t` 唯一索引 `uk_code` 字段 `code` 不能忽略，转换时将终止。",
+    }
+    if !reflect.DeepEqual(c.plan.Notes, want) {
+        t.Errorf("Notes = %q, want %q", c.plan.Notes, want)
+    }
+}
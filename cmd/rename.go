@@ -0,0 +1,134 @@
+package cmd
+
+import (
+    "fmt"
+    "strings"
+    "unicode"
+)
+
+// renameTables 按配置文件 rename 计算各表（含视图）的 SQLite 表名，重命名后重名报错。
+func renameTables(serverTableData []*Table) error {
+    if renameConfig == nil {
+        return nil
+    }
+
+    names := make(map[string]string, len(serverTableData))
+    for _, serverTable := range serverTableData {
+        if isIgnoreTable(serverTable.TableName) {
+            continue
+        }
+        name := renameTable(serverTable.TableName)
+        if other, ok := names[strings.ToLower(name)]; ok {
+            return fmt.Errorf("表 `%s`、`%s` 重命名后同为 `%s`。", other, serverTable.TableName, name)
+        }
+        names[strings.ToLower(name)] = serverTable.TableName
+        tableNameMap[serverTable.TableName] = name
+    }
+    return nil
+}
+
+// renameTable SQLite 表名: tables 指定的 name，否则去除 strip_prefix 后按 case 转换。
+func renameTable(tableName string) string {
+    if renameConfig == nil {
+        return tableName
+    }
+    if name, ok := tableNameMap[tableName]; ok {
+        return name
+    }
+    for _, rule := range renameConfig.Tables {
+        if rule.Name != "" && rule.pattern.match(tableName) {
+            return rule.Name
+        }
+    }
+    name := tableName
+    if trimmed := strings.TrimPrefix(tableName, renameConfig.StripPrefix); trimmed != "" {
+        name = trimmed
+    }
+    return convertCase(name, renameConfig.Case)
+}
+
+// renameColumn SQLite 字段名: tables 指定的 columns 映射，否则按 case 转换。
+func renameColumn(tableName, columnName string) string {
+    if renameConfig == nil {
+        return columnName
+    }
+    for _, rule := range renameConfig.Tables {
+        if name, ok := rule.Columns[columnName]; ok && rule.pattern.match(tableName) {
+            return name
+        }
+    }
+    return convertCase(columnName, renameConfig.Case)
+}
+
+// renameIndex SQLite 索引名、外键约束名: tables 指定的 indexes 映射，
+// 否则名称中以 _ 分隔的原表名替换为新表名后按 case 转换。
+func renameIndex(tableName, indexName string) string {
+    if renameConfig == nil {
+        return indexName
+    }
+    for _, rule := range renameConfig.Tables {
+        if name, ok := rule.Indexes[indexName]; ok && rule.pattern.match(tableName) {
+            return name
+        }
+    }
+    if name := renameTable(tableName); name != tableName {
+        indexName = replaceNameToken(indexName, tableName, name)
+    }
+    return convertCase(indexName, renameConfig.Case)
+}
+
+// replaceNameToken 替换名称中前后为 _ 或首尾的 old，不替换其他名称的一部分。(idx_user_name 中的 user，不含 idx_username)
+func replaceNameToken(name, old, new string) string {
+    var b strings.Builder
+    for i := 0; i < len(name); {
+        j := strings.Index(name[i:], old)
+        if j < 0 {
+            b.WriteString(name[i:])
+            break
+        }
+        j += i
+        end := j + len(old)
+        if (j == 0 || name[j-1] == '_') && (end == len(name) || name[end] == '_') {
+            b.WriteString(name[i:j])
+            b.WriteString(new)
+            i = end
+        } else {
+            b.WriteString(name[i : j+1])
+            i = j + 1
+        }
+    }
+    return b.String()
+}
+
+// convertCase 名称大小写转换: snake 转为 snake_case，lower 转为小写。
+func convertCase(name string, mode string) string {
+    switch mode {
+    case CaseSnake:
+        return toSnakeCase(name)
+    case CaseLower:
+        return strings.ToLower(name)
+    }
+    return name
+}
+
+// toSnakeCase 转为 snake_case: UserID -> user_id，HTTPServer -> http_server，user-name -> user_name。
+func toSnakeCase(name string) string {
+    var (
+        sb strings.Builder
+        rs = []rune(name)
+    )
+    for i, r := range rs {
+        if r == '-' || r == ' ' || r == '.' {
+            r = '_'
+        }
+        if unicode.IsUpper(r) && i > 0 {
+            prev := rs[i-1]
+            if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+                (unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+                sb.WriteRune('_')
+            }
+        }
+        sb.WriteRune(unicode.ToLower(r))
+    }
+    return sb.String()
+}
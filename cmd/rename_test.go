@@ -0,0 +1,140 @@
+package cmd
+
+import "testing"
+
+func TestToSnakeCase(t *testing.T) {
+    tests := []struct {
+        name string
+        want string
+    }{
+        {"UserID", "user_id"},
+        {"HTTPServer", "http_server"},
+        {"getHTTPResponseCode", "get_http_response_code"},
+        {"user-name", "user_name"},
+        {"user name", "user_name"},
+        {"Level2Boss", "level2_boss"},
+        {"ID", "id"},
+        {"already_snake", "already_snake"},
+    }
+
+    for _, tt := range tests {
+        if got := toSnakeCase(tt.name); got != tt.want {
+            t.Errorf("toSnakeCase(%q) = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestRename(t *testing.T) {
+    defer func(config *RenameConfig, nameMap map[string]string) {
+        renameConfig, tableNameMap = config, nameMap
+    }(renameConfig, tableNameMap)
+
+    renameConfig = &RenameConfig{
+        StripPrefix: "t_",
+        Case:        CaseSnake,
+        Tables: []*RenameTable{
+            {Table: "t_UserLog", Name: "logs"},
+            {Table: "/^t_order/", Columns: map[string]string{"OrderID": "id"}, Indexes: map[string]string{"idx_OrderTime": "idx_time"}},
+        },
+    }
+    for _, rule := range renameConfig.Tables {
+        var err error
+        if rule.pattern, err = parseNamePattern(rule.Table); err != nil {
+            t.Fatal(err)
+        }
+    }
+    tableNameMap = make(map[string]string)
+
+    tables := []struct {
+        table string
+        want  string
+    }{
+        {"t_UserInfo", "user_info"},
+        {"t_UserLog", "logs"},
+        {"HTTPServer", "http_server"},
+        {"t_", "t_"}, // 去除前缀后为空时保留原名
+    }
+    for _, tt := range tables {
+        if got := renameTable(tt.table); got != tt.want {
+            t.Errorf("renameTable(%q) = %q, want %q", tt.table, got, tt.want)
+        }
+    }
+
+    columns := []struct {
+        table  string
+        column string
+        want   string
+    }{
+        {"t_order", "OrderID", "id"},
+        {"t_order_item", "OrderID", "id"},
+        {"t_UserInfo", "OrderID", "order_id"},
+        {"t_UserInfo", "CreatedAt", "created_at"},
+    }
+    for _, tt := range columns {
+        if got := renameColumn(tt.table, tt.column); got != tt.want {
+            t.Errorf("renameColumn(%q, %q) = %q, want %q", tt.table, tt.column, got, tt.want)
+        }
+    }
+
+    indexes := []struct {
+        table string
+        index string
+        want  string
+    }{
+        {"t_order", "idx_OrderTime", "idx_time"},
+        {"t_UserInfo", "idx_t_UserInfo_Name", "idx_user_info_name"},
+        {"t_UserLog", "uk_t_UserLog", "uk_logs"},
+        {"t_UserInfo", "fk_UserInfo_Level", "fk_user_info_level"},
+        {"t_UserInfo", "idx_t_UserInfoExt_Name", "idx_t_user_info_ext_name"}, // 其他名称的一部分不替换
+        {"t_UserInfo", "t_UserInfo_t_UserInfo", "user_info_user_info"},
+        {"t_UserInfo", "idx_at_UserInfo", "idx_at_user_info"},
+    }
+    for _, tt := range indexes {
+        if got := renameIndex(tt.table, tt.index); got != tt.want {
+            t.Errorf("renameIndex(%q, %q) = %q, want %q", tt.table, tt.index, got, tt.want)
+        }
+    }
+}
+
+func TestReplaceNameToken(t *testing.T) {
+    tests := []struct {
+        name string
+        want string
+    }{
+        {"idx_user_name", "idx_player_name"},
+        {"user", "player"},
+        {"user_idx", "player_idx"},
+        {"idx_username", "idx_username"},
+        {"idx_super_user", "idx_super_player"},
+        {"idx_superuser", "idx_superuser"},
+        {"user_user", "player_player"},
+        {"users_user", "users_player"},
+    }
+    for _, tt := range tests {
+        if got := replaceNameToken(tt.name, "user", "player"); got != tt.want {
+            t.Errorf("replaceNameToken(%q) = %q, want %q", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestRenameTablesConflict(t *testing.T) {
+    defer func(config *RenameConfig, nameMap map[string]string) {
+        renameConfig, tableNameMap = config, nameMap
+    }(renameConfig, tableNameMap)
+
+    renameConfig = &RenameConfig{StripPrefix: "t_", Case: CaseLower}
+    tableNameMap = make(map[string]string)
+
+    err := renameTables([]*Table{{TableName: "t_User"}, {TableName: "user"}})
+    if err == nil {
+        t.Fatalf("renameTables() error = nil, want conflict")
+    }
+
+    tableNameMap = make(map[string]string)
+    if err = renameTables([]*Table{{TableName: "t_User"}, {TableName: "t_Item"}}); err != nil {
+        t.Fatal(err)
+    }
+    if tableNameMap["t_User"] != "user" || tableNameMap["t_Item"] != "item" {
+        t.Errorf("tableNameMap = %v, want t_User:user t_Item:item", tableNameMap)
+    }
+}
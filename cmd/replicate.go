@@ -0,0 +1,619 @@
+package cmd
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/camry/g/glog"
+    "github.com/spf13/cobra"
+    "gorm.io/gorm"
+)
+
+// BinlogTableName binlog 同步位置表。
+const BinlogTableName = "mysql2sqlite_binlog"
+
+var createBinlogTableSql = fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (\n  `id` INTEGER NOT NULL PRIMARY KEY CHECK (`id` = 1),\n  `file` TEXT NOT NULL,\n  `position` INTEGER NOT NULL,\n  `synced_at` TEXT NOT NULL\n)", BinlogTableName)
+
+var replicateCmd = &cobra.Command{
+    Use:   "replicate",
+    Short: "Replicate MySQL to SQLite3 via binlog.",
+    Long:  "首次运行在一致性快照中全量导出并记录 binlog 位置，随后持续读取行格式 binlog，将 INSERT/UPDATE/DELETE 应用到 SQLite 数据库。再次运行从记录的位置继续同步。",
+    Run: func(cmd *cobra.Command, args []string) {
+        checkFlags()
+        if output == "" || strings.HasSuffix(strings.ToLower(output), ".sql") {
+            cobra.CheckErr(fmt.Errorf("binlog 同步仅支持直接写入 SQLite 数据库。(--output <*.db>)"))
+        }
+
+        serverDbConfig, serverDb, serverTableData := openServerDb()
+        loadConfig()
+        cobra.CheckErr(renameTables(serverTableData))
+        if len(filterMap) > 0 || subsetConfig != nil {
+            // binlog 行变更无法按 where 条件、子集关联过滤，同步后会混入范围外的行。
+            cobra.CheckErr(fmt.Errorf("binlog 同步不支持配置文件 filters、subset。"))
+        }
+        cobra.CheckErr(checkBinlogFormat(serverDb))
+        location, err := getServerLocation(serverDb)
+        cobra.CheckErr(err)
+
+        sqliteWriter, err := NewSQLiteWriter(output)
+        cobra.CheckErr(err)
+        file, position, err := getReplicaPosition(sqliteWriter.db)
+        cobra.CheckErr(err)
+
+        var converterMap map[string]*Converter
+        if file == "" {
+            // 初始导出: 快照与 binlog 位置在同一全局读锁内获取。
+            snapshot, err = NewSnapshot(serverDb, limitSnapshotSize(), true)
+            cobra.CheckErr(err)
+            converterMap = convert(sqliteWriter, serverDbConfig, serverDb, serverTableData)
+            snapshot.Close()
+            file, position, snapshot = snapshot.BinlogFile, snapshot.BinlogPosition, nil
+
+            sqliteWriter, err = NewSQLiteWriter(output)
+            cobra.CheckErr(err)
+            cobra.CheckErr(setReplicaPosition(sqliteWriter.db, file, position))
+        } else {
+            converterMap = make(map[string]*Converter, len(serverTableData))
+            for _, serverTable := range serverTableData {
+                ignoreTable := getIgnoreTable(serverTable.TableName)
+                if ignoreTable == nil || serverTable.TableType != "BASE TABLE" {
+                    continue
+                }
+                converter := NewConverter(serverDbConfig, serverDb, serverTable, ignoreTable, discardWriter{})
+                converter.create()
+                converterMap[serverTable.TableName] = converter
+            }
+        }
+
+        replicator, err := NewReplicator(sqliteWriter, serverDb, converterMap, file, position)
+        cobra.CheckErr(err)
+
+        syncer, err := NewBinlogSyncer(serverDbConfig, serverID, file, position, location)
+        cobra.CheckErr(err)
+        defer syncer.Close()
+
+        glog.Infof("从 binlog %s:%d 开始同步。", file, position)
+        cobra.CheckErr(replicator.Run(syncer))
+    },
+}
+
+func init() {
+    replicateCmd.Flags().Uint32Var(&serverID, "server-id", 1001, "作为从库连接时使用的 server_id，不能与其他从库重复。")
+
+    rootCmd.AddCommand(replicateCmd)
+}
+
+// checkBinlogFormat 校验 binlog 为 ROW 格式并记录完整行镜像。
+func checkBinlogFormat(serverDb *gorm.DB) error {
+    var format, rowImage string
+    if err := serverDb.Raw("SELECT @@GLOBAL.binlog_format, @@GLOBAL.binlog_row_image").Row().Scan(&format, &rowImage); err != nil {
+        return fmt.Errorf("无法读取 binlog 配置: %w", err)
+    }
+    if !strings.EqualFold(format, "ROW") {
+        return fmt.Errorf("binlog_format 为 %s，binlog 同步需要 ROW。", format)
+    }
+    if !strings.EqualFold(rowImage, "FULL") {
+        return fmt.Errorf("binlog_row_image 为 %s，binlog 同步需要 FULL。", rowImage)
+    }
+    return nil
+}
+
+// getServerLocation 导出连接的会话时区，binlog 中的 TIMESTAMP 按该时区转换，与全量导出一致。
+func getServerLocation(serverDb *gorm.DB) (*time.Location, error) {
+    var offset int
+    if err := serverDb.Raw("SELECT TIMESTAMPDIFF(SECOND, UTC_TIMESTAMP(), NOW())").Row().Scan(&offset); err != nil {
+        return nil, err
+    }
+    return time.FixedZone("", offset), nil
+}
+
+// getReplicaPosition 已记录的 binlog 同步位置，未同步过返回空文件名。
+func getReplicaPosition(db *gorm.DB) (string, uint32, error) {
+    var positionData []struct {
+        File     string
+        Position uint32
+    }
+
+    if err := db.Exec(createBinlogTableSql).Error; err != nil {
+        return "", 0, err
+    }
+    if err := db.Raw(fmt.Sprintf("SELECT `file`, `position` FROM `%s` WHERE `id` = 1", BinlogTableName)).Scan(&positionData).Error; err != nil {
+        return "", 0, err
+    }
+    if len(positionData) == 0 {
+        return "", 0, nil
+    }
+    return positionData[0].File, positionData[0].Position, nil
+}
+
+// setReplicaPosition 记录 binlog 同步位置。
+func setReplicaPosition(db *gorm.DB, file string, position uint32) error {
+    if err := db.Exec(createBinlogTableSql).Error; err != nil {
+        return err
+    }
+    return db.Exec(fmt.Sprintf("INSERT INTO `%s` (`id`,`file`,`position`,`synced_at`) VALUES (1,?,?,CURRENT_TIMESTAMP) ON CONFLICT (`id`) DO UPDATE SET `file` = excluded.`file`, `position` = excluded.`position`, `synced_at` = excluded.`synced_at`", BinlogTableName),
+        file, position,
+    ).Error
+}
+
+// discardWriter 丢弃输出，用于仅解析表结构。
+type discardWriter struct{}
+
+func (discardWriter) Create(string, []string) error                                 { return nil }
+func (discardWriter) Insert(string, []*MySQL2SQLiteColumn, [][]any) error           { return nil }
+func (discardWriter) Upsert(string, []*MySQL2SQLiteColumn, []string, [][]any) error { return nil }
+func (discardWriter) SetWatermark(string, string, string) error                     { return nil }
+
+// replicaTable 同步表: binlog 行镜像按 MySQL 字段顺序记录全部字段。
+type replicaTable struct {
+    converter *Converter
+    columns   []Column              // MySQL 全部字段
+    targets   []*MySQL2SQLiteColumn // 对应的 SQLite 字段，忽略的字段为 nil
+    members   [][]string            // ENUM、SET 成员
+}
+
+// Replicator 将 binlog 行变更应用到 SQLite 数据库。
+type Replicator struct {
+    writer   *SQLiteWriter
+    tables   map[string]*replicaTable
+    file     string
+    position uint32
+    tx       *gorm.DB
+    warned   map[string]bool
+}
+
+// NewReplicator 新建 binlog 同步，converterMap 为已解析表结构的各表转换器。
+func NewReplicator(writer *SQLiteWriter, serverDb *gorm.DB, converterMap map[string]*Converter, file string, position uint32) (*Replicator, error) {
+    r := &Replicator{
+        writer:   writer,
+        tables:   make(map[string]*replicaTable, len(converterMap)),
+        file:     file,
+        position: position,
+        warned:   make(map[string]bool),
+    }
+
+    for tableName, converter := range converterMap {
+        if converter.serverTable.TableType != "BASE TABLE" || len(converter.serverTableColumns) == 0 {
+            continue
+        }
+
+        var serverColumnData []Column
+        err := serverDb.Table("COLUMNS").Order("`ORDINAL_POSITION` ASC").Find(
+            &serverColumnData,
+            "`TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?",
+            converter.serverDbConfig.Database, tableName,
+        ).Error
+        if err != nil {
+            return nil, err
+        }
+        r.tables[tableName] = newReplicaTable(converter, serverColumnData)
+    }
+    return r, nil
+}
+
+// newReplicaTable 新建同步表。
+func newReplicaTable(converter *Converter, serverColumnData []Column) *replicaTable {
+    t := &replicaTable{converter: converter, columns: serverColumnData}
+    for _, serverColumn := range serverColumnData {
+        var target *MySQL2SQLiteColumn
+        for _, column := range converter.serverTableColumns {
+            if column.ColumnName == serverColumn.ColumnName {
+                target = column
+            }
+        }
+        t.targets = append(t.targets, target)
+        t.members = append(t.members, getMembers(serverColumn.ColumnType))
+    }
+    return t
+}
+
+// Run 读取 binlog 事件并应用，事务提交时记录同步位置。
+func (r *Replicator) Run(streamer BinlogStreamer) error {
+    if err := r.writer.Begin(); err != nil {
+        return err
+    }
+    defer r.writer.End()
+
+    for {
+        event, err := streamer.GetEvent()
+        if err != nil {
+            r.rollback()
+            if errors.Is(err, io.EOF) {
+                // 服务器重启或关闭连接，已提交的位置已记录。
+                return fmt.Errorf("binlog 连接已断开，再次运行从 %s:%d 继续同步: %w", r.file, r.position, err)
+            }
+            return err
+        }
+
+        switch e := event.Event.(type) {
+        case *RotateEvent:
+            r.file, r.position = e.File, uint32(e.Position)
+            if r.tx == nil {
+                if err = setReplicaPosition(r.writer.db, r.file, r.position); err != nil {
+                    return err
+                }
+            }
+        case *RowsEvent:
+            if err = r.applyRows(e); err != nil {
+                r.rollback()
+                return err
+            }
+        case *XIDEvent:
+            r.position = event.Header.LogPos
+            if err = r.commit(); err != nil {
+                return err
+            }
+        case *QueryEvent:
+            r.position = event.Header.LogPos
+            switch strings.ToUpper(strings.TrimSpace(e.Query)) {
+            case "BEGIN":
+                continue
+            case "COMMIT":
+            default:
+                if r.isReplicatedDDL(e) {
+                    glog.Warnf("表结构变更不会同步，请重新导出: %s", e.Query)
+                }
+            }
+            if err = r.commit(); err != nil {
+                return err
+            }
+        }
+    }
+}
+
+// isReplicatedDDL 语句是否变更了同步表的表结构，无法解析的语句按已变更处理。
+func (r *Replicator) isReplicatedDDL(e *QueryEvent) bool {
+    names, err := getDDLTables(e.Query, e.Schema)
+    if err != nil {
+        return e.Schema == db
+    }
+    for _, name := range names {
+        if name[0] == db && r.tables[name[1]] != nil {
+            return true
+        }
+    }
+    return false
+}
+
+// getDDLTables CREATE、ALTER、DROP、RENAME、TRUNCATE 语句涉及的表（库名、表名），未指定库名时为 schema。
+// RENAME 同时返回原表名与新表名，其他语句返回 nil。
+func getDDLTables(query string, schema string) ([][2]string, error) {
+    tokens, err := tokenize(query)
+    if err != nil {
+        return nil, err
+    }
+    var ts []token
+    for _, tk := range tokens {
+        if tk.kind != tokenSpace {
+            ts = append(ts, tk)
+        }
+    }
+    keyword := func(i int) string {
+        if i < len(ts) && ts[i].kind == tokenIdent {
+            return strings.ToUpper(ts[i].text)
+        }
+        return ""
+    }
+
+    var names [][2]string
+    // name 读取第 i 个词法单元起的 [库名.]表名，返回下一个词法单元下标。
+    name := func(i int) int {
+        if i >= len(ts) || (ts[i].kind != tokenIdent && ts[i].kind != tokenQuoted) {
+            return i
+        }
+        n := [2]string{schema, getIdent(ts[i])}
+        if i+2 < len(ts) && ts[i+1].text == "." && (ts[i+2].kind == tokenIdent || ts[i+2].kind == tokenQuoted) {
+            n = [2]string{n[1], getIdent(ts[i+2])}
+            i += 2
+        }
+        names = append(names, n)
+        return i + 1
+    }
+    // list 读取逗号分隔的表名，each 为各表名之后的处理。
+    list := func(i int, each func(int) int) {
+        for i < len(ts) {
+            if i = each(name(i)); i >= len(ts) || ts[i].text != "," {
+                return
+            }
+            i++
+        }
+    }
+    // table 跳过 IF [NOT] EXISTS。
+    table := func(i int) int {
+        if keyword(i) == "IF" {
+            i++
+            if keyword(i) == "NOT" {
+                i++
+            }
+            i++
+        }
+        return i
+    }
+
+    statement := keyword(0)
+    for i := 1; i < len(ts); i++ {
+        switch keyword(i) {
+        case "TABLE":
+            i = table(i + 1)
+            switch statement {
+            case "DROP":
+                list(i, func(j int) int { return j })
+            case "RENAME":
+                list(i, func(j int) int {
+                    if keyword(j) == "TO" {
+                        return name(j + 1)
+                    }
+                    return j
+                })
+            case "CREATE", "TRUNCATE":
+                name(i)
+            case "ALTER":
+                for j := name(i); j < len(ts); j++ {
+                    // ALTER TABLE t RENAME [TO | AS] t2，不含 RENAME COLUMN、RENAME INDEX。
+                    if keyword(j) != "RENAME" {
+                        continue
+                    }
+                    switch keyword(j + 1) {
+                    case "COLUMN", "INDEX", "KEY":
+                    case "TO", "AS":
+                        name(j + 2)
+                    default:
+                        name(j + 1)
+                    }
+                }
+            }
+            return names, nil
+        case "INDEX":
+            // CREATE [UNIQUE | FULLTEXT | SPATIAL] INDEX i ON t，DROP INDEX i ON t。
+            if statement == "CREATE" || statement == "DROP" {
+                for j := i + 1; j < len(ts); j++ {
+                    if keyword(j) == "ON" {
+                        name(j + 1)
+                        break
+                    }
+                }
+            }
+            return names, nil
+        }
+    }
+    if statement == "TRUNCATE" {
+        // TRUNCATE t
+        name(1)
+    }
+    return names, nil
+}
+
+// getIdent 标识符名称。
+func getIdent(tk token) string {
+    if tk.kind == tokenQuoted {
+        return tk.value
+    }
+    return tk.text
+}
+
+// begin 开始 SQLite 事务。
+func (r *Replicator) begin() error {
+    if r.tx == nil {
+        r.tx = r.writer.db.Begin()
+        return r.tx.Error
+    }
+    return nil
+}
+
+// commit 记录同步位置并提交 SQLite 事务。
+func (r *Replicator) commit() error {
+    if err := r.begin(); err != nil {
+        return err
+    }
+    tx := r.tx
+    r.tx = nil
+    if err := setReplicaPosition(tx, r.file, r.position); err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit().Error
+}
+
+// rollback 回滚未提交的 SQLite 事务。
+func (r *Replicator) rollback() {
+    if r.tx != nil {
+        r.tx.Rollback()
+        r.tx = nil
+    }
+}
+
+// applyRows 应用行变更。
+func (r *Replicator) applyRows(e *RowsEvent) error {
+    if e.Table.Schema != db {
+        return nil
+    }
+    t, ok := r.tables[e.Table.Table]
+    if !ok {
+        if getIgnoreTable(e.Table.Table) != nil && !r.warned[e.Table.Table] {
+            r.warned[e.Table.Table] = true
+            glog.Warnf("表 `%s` 不在初始导出中，变更不会同步。", e.Table.Table)
+        }
+        return nil
+    }
+    if len(e.Table.ColumnTypes) != len(t.columns) {
+        return fmt.Errorf("表 `%s` binlog 字段数 %d 与当前表结构 %d 不一致，请重新导出。", e.Table.Table, len(e.Table.ColumnTypes), len(t.columns))
+    }
+
+    if err := r.begin(); err != nil {
+        return err
+    }
+    tableName := t.converter.sqliteTableName
+    for i := range e.After {
+        if e.Before != nil {
+            if err := r.update(t, e.Before[i], e.BeforePresent, e.After[i], e.AfterPresent); err != nil {
+                return fmt.Errorf("表 `%s` 更新失败: %w", tableName, err)
+            }
+        } else if err := r.insert(t, e.After[i], e.AfterPresent); err != nil {
+            return fmt.Errorf("表 `%s` 写入失败: %w", tableName, err)
+        }
+    }
+    if e.After == nil {
+        for i := range e.Before {
+            if err := r.delete(t, e.Before[i], e.BeforePresent); err != nil {
+                return fmt.Errorf("表 `%s` 删除失败: %w", tableName, err)
+            }
+        }
+    }
+    return nil
+}
+
+// insert 写入一行，有主键（或非空唯一索引）时冲突则更新。
+func (r *Replicator) insert(t *replicaTable, row []any, present []bool) error {
+    columns, values := t.getValues(row, present)
+    var ks, ps []string
+    for _, column := range columns {
+        ks = append(ks, fmt.Sprintf("`%s`", column.SQLiteColumnName))
+        ps = append(ps, "?")
+    }
+    tableName := t.converter.sqliteTableName
+    placeholders := fmt.Sprintf("(%s)", strings.Join(ps, ","))
+
+    if len(t.converter.serverTableKeys) > 0 {
+        return r.tx.Exec(getUpsertSql(tableName, columns, t.converter.getSQLiteKeys(), placeholders), values...).Error
+    }
+    return r.tx.Exec(fmt.Sprintf("INSERT INTO `%s` (%s) VALUES %s", tableName, strings.Join(ks, ","), placeholders), values...).Error
+}
+
+// update 按更新前镜像定位并更新一行。
+func (r *Replicator) update(t *replicaTable, before []any, beforePresent []bool, after []any, afterPresent []bool) error {
+    columns, values := t.getValues(after, afterPresent)
+    if len(columns) == 0 {
+        return nil
+    }
+    var ss []string
+    for _, column := range columns {
+        ss = append(ss, fmt.Sprintf("`%s` = ?", column.SQLiteColumnName))
+    }
+    where, args := t.getWhere(before, beforePresent)
+
+    return r.tx.Exec(fmt.Sprintf("UPDATE `%s` SET %s WHERE %s", t.converter.sqliteTableName, strings.Join(ss, ", "), where),
+        append(values, args...)...,
+    ).Error
+}
+
+// delete 按删除前镜像定位并删除一行。
+func (r *Replicator) delete(t *replicaTable, before []any, beforePresent []bool) error {
+    where, args := t.getWhere(before, beforePresent)
+    return r.tx.Exec(fmt.Sprintf("DELETE FROM `%s` WHERE %s", t.converter.sqliteTableName, where), args...).Error
+}
+
+// getValues 行镜像转换为 SQLite 字段及值，跳过忽略的字段及生成列。
+func (t *replicaTable) getValues(row []any, present []bool) ([]*MySQL2SQLiteColumn, []any) {
+    var (
+        columns []*MySQL2SQLiteColumn
+        values  []any
+    )
+    for i, target := range t.targets {
+        if target == nil || target.Generated || !present[i] {
+            continue
+        }
+        columns = append(columns, target)
+        values = append(values, t.getValue(i, row[i]))
+    }
+    return columns, values
+}
+
+// getWhere 定位一行的条件: 有主键（或非空唯一索引）时按键匹配，否则按全部字段匹配其中一行。
+func (t *replicaTable) getWhere(row []any, present []bool) (string, []any) {
+    var (
+        ands []string
+        args []any
+    )
+    keys := t.converter.serverTableKeys
+    for i, target := range t.targets {
+        if target == nil || !present[i] {
+            continue
+        }
+        if len(keys) > 0 {
+            for _, key := range keys {
+                if key == target.ColumnName {
+                    ands = append(ands, fmt.Sprintf("`%s` = ?", target.SQLiteColumnName))
+                    args = append(args, t.getValue(i, row[i]))
+                }
+            }
+        } else if !target.Generated {
+            ands = append(ands, fmt.Sprintf("`%s` IS ?", target.SQLiteColumnName))
+            args = append(args, t.getValue(i, row[i]))
+        }
+    }
+
+    where := strings.Join(ands, " AND ")
+    if len(keys) == 0 {
+        where = fmt.Sprintf("rowid IN (SELECT rowid FROM `%s` WHERE %s LIMIT 1)", t.converter.sqliteTableName, where)
+    }
+    return where, args
+}
+
+// getValue binlog 字段值转换为与全量导出一致的 SQLite 字段值。
+func (t *replicaTable) getValue(i int, value any) any {
+    if value == nil {
+        return nil
+    }
+    column := t.columns[i]
+
+    switch v := value.(type) {
+    case binlogEnum:
+        value = ""
+        if v > 0 && int(v) <= len(t.members[i]) {
+            value = t.members[i][v-1]
+        }
+    case binlogSet:
+        var ms []string
+        for j, member := range t.members[i] {
+            if v&(1<<j) != 0 {
+                ms = append(ms, member)
+            }
+        }
+        value = strings.Join(ms, ",")
+    case int64:
+        if strings.Contains(column.ColumnType, "unsigned") {
+            value = getUnsigned(strings.ToUpper(column.DataType), v)
+        }
+    case []byte:
+        value = string(v)
+    }
+    return maskValue(t.targets[i], t.converter.getValue(t.targets[i], value))
+}
+
+// getUnsigned 无符号整数按字段宽度还原，超出 int64 的 BIGINT UNSIGNED 返回十进制字符串。
+func getUnsigned(dataType string, v int64) any {
+    switch dataType {
+    case "TINYINT":
+        return int64(uint8(v))
+    case "SMALLINT":
+        return int64(uint16(v))
+    case "MEDIUMINT":
+        return v & 0xffffff
+    case "INT", "INTEGER":
+        return int64(uint32(v))
+    case "BIGINT":
+        if v < 0 {
+            return strconv.FormatUint(uint64(v), 10)
+        }
+    }
+    return v
+}
+
+var membersPattern = regexp.MustCompile(`'((?:[^']|'')*)'`)
+
+// getMembers ENUM、SET 字段成员: enum('a','b')
+func getMembers(columnType string) []string {
+    if !strings.HasPrefix(columnType, "enum(") && !strings.HasPrefix(columnType, "set(") {
+        return nil
+    }
+    var members []string
+    for _, match := range membersPattern.FindAllStringSubmatch(columnType, -1) {
+        members = append(members, strings.ReplaceAll(match[1], "''", "'"))
+    }
+    return members
+}
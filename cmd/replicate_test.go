@@ -0,0 +1,233 @@
+package cmd
+
+import (
+    "errors"
+    "io"
+    "path/filepath"
+    "reflect"
+    "testing"
+
+    "github.com/go-mysql-org/go-mysql/mysql"
+)
+
+// fakeStreamer 按顺序返回事件，读完后返回 io.EOF（模拟服务器断开）。
+type fakeStreamer struct {
+    events []*BinlogEvent
+}
+
+func (s *fakeStreamer) GetEvent() (*BinlogEvent, error) {
+    if len(s.events) == 0 {
+        return nil, io.EOF
+    }
+    event := s.events[0]
+    s.events = s.events[1:]
+    return event, nil
+}
+
+func (s *fakeStreamer) Close() error {
+    return nil
+}
+
+func TestReplicatorRun(t *testing.T) {
+    defer func(v string) { db = v }(db)
+    db = "game"
+
+    path := filepath.Join(t.TempDir(), "game.db")
+    writer, err := NewSQLiteWriter(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = writer.db.Exec("CREATE TABLE `player` (`id` INTEGER NOT NULL PRIMARY KEY, `name` TEXT NOT NULL, `score` REAL NOT NULL, `status` TEXT NOT NULL)").Error
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    converter := &Converter{
+        serverTable:     &Table{TableName: "player", TableType: "BASE TABLE"},
+        sqliteTableName: "player",
+        serverTableColumns: []*MySQL2SQLiteColumn{
+            {ColumnName: "id", SQLiteColumnName: "id", DataType: "BIGINT", SQLiteDataType: "INTEGER"},
+            {ColumnName: "name", SQLiteColumnName: "name", DataType: "VARCHAR", SQLiteDataType: "TEXT"},
+            {ColumnName: "score", SQLiteColumnName: "score", DataType: "DECIMAL", SQLiteDataType: "REAL", NumericScale: 2},
+            {ColumnName: "status", SQLiteColumnName: "status", DataType: "ENUM", SQLiteDataType: "TEXT"},
+        },
+        serverTableKeys: []string{"id"},
+    }
+    table := newReplicaTable(converter, []Column{
+        {ColumnName: "id", DataType: "bigint", ColumnType: "bigint"},
+        {ColumnName: "name", DataType: "varchar", ColumnType: "varchar(64)"},
+        {ColumnName: "score", DataType: "decimal", ColumnType: "decimal(10,2)"},
+        {ColumnName: "status", DataType: "enum", ColumnType: "enum('on','off')"},
+    })
+    replicator := &Replicator{
+        writer:   writer,
+        tables:   map[string]*replicaTable{"player": table},
+        file:     "binlog.000001",
+        position: 4,
+        warned:   make(map[string]bool),
+    }
+
+    tableMap := &TableMapEvent{
+        TableID:     1,
+        Schema:      "game",
+        Table:       "player",
+        ColumnTypes: []byte{mysql.MYSQL_TYPE_LONGLONG, mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_NEWDECIMAL, mysql.MYSQL_TYPE_STRING},
+        ColumnMeta:  []uint16{0, 256, 10<<8 | 2, uint16(mysql.MYSQL_TYPE_ENUM)<<8 | 1},
+    }
+    present := []bool{true, true, true, true}
+    streamer := &fakeStreamer{events: []*BinlogEvent{
+        {Event: &RotateEvent{Position: 4, File: "binlog.000002"}},
+        {Header: BinlogEventHeader{LogPos: 100}, Event: &QueryEvent{Schema: "game", Query: "BEGIN"}},
+        {Header: BinlogEventHeader{LogPos: 150}, Event: tableMap},
+        {Header: BinlogEventHeader{LogPos: 200}, Event: &RowsEvent{
+            Table: tableMap,
+            After: [][]any{
+                {int64(1), []byte("a"), "1.50", binlogEnum(1)},
+                {int64(2), []byte("b"), "2.00", binlogEnum(2)},
+            },
+            AfterPresent: present,
+        }},
+        {Header: BinlogEventHeader{LogPos: 250}, Event: &XIDEvent{XID: 1}},
+        {Header: BinlogEventHeader{LogPos: 300}, Event: &QueryEvent{Schema: "game", Query: "BEGIN"}},
+        {Header: BinlogEventHeader{LogPos: 350}, Event: tableMap},
+        {Header: BinlogEventHeader{LogPos: 400}, Event: &RowsEvent{
+            Table:         tableMap,
+            Before:        [][]any{{int64(1), []byte("a"), "1.50", binlogEnum(1)}},
+            After:         [][]any{{int64(1), []byte("a'b"), "3.25", binlogEnum(2)}},
+            BeforePresent: present,
+            AfterPresent:  present,
+        }},
+        {Header: BinlogEventHeader{LogPos: 450}, Event: &RowsEvent{
+            Table:         tableMap,
+            Before:        [][]any{{int64(2), []byte("b"), "2.00", binlogEnum(2)}},
+            BeforePresent: present,
+        }},
+        {Header: BinlogEventHeader{LogPos: 500}, Event: &XIDEvent{XID: 2}},
+        {Header: BinlogEventHeader{LogPos: 600}, Event: &QueryEvent{Schema: "game", Query: "ALTER TABLE `player` ADD `level` int"}},
+        // 未提交的事务在断开时回滚，位置停留在上一次提交。
+        {Header: BinlogEventHeader{LogPos: 700}, Event: &QueryEvent{Schema: "game", Query: "BEGIN"}},
+        {Header: BinlogEventHeader{LogPos: 750}, Event: tableMap},
+        {Header: BinlogEventHeader{LogPos: 800}, Event: &RowsEvent{
+            Table:        tableMap,
+            After:        [][]any{{int64(3), []byte("c"), "0.00", binlogEnum(1)}},
+            AfterPresent: present,
+        }},
+    }}
+
+    if err = replicator.Run(streamer); !errors.Is(err, io.EOF) {
+        t.Fatalf("Run() error = %v, want io.EOF", err)
+    }
+
+    writer, err = NewSQLiteWriter(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer writer.End()
+
+    var rows []struct {
+        ID     int64
+        Name   string
+        Score  float64
+        Status string
+    }
+    if err = writer.db.Raw("SELECT `id`, `name`, `score`, `status` FROM `player` ORDER BY `id`").Scan(&rows).Error; err != nil {
+        t.Fatal(err)
+    }
+    if len(rows) != 1 || rows[0].ID != 1 || rows[0].Name != "a'b" || rows[0].Score != 3.25 || rows[0].Status != "off" {
+        t.Errorf("rows = %+v, want [{ID:1 Name:a'b Score:3.25 Status:off}]", rows)
+    }
+
+    file, position, err := getReplicaPosition(writer.db)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if file != "binlog.000002" || position != 600 {
+        t.Errorf("position = %s:%d, want binlog.000002:600", file, position)
+    }
+}
+
+func TestGetDDLTables(t *testing.T) {
+    tests := []struct {
+        name  string
+        query string
+        want  [][2]string
+    }{
+        {name: "alter", query: "ALTER TABLE `player` ADD `level` int", want: [][2]string{{"game", "player"}}},
+        {name: "qualified", query: "alter table `other`.player add `level` int", want: [][2]string{{"other", "player"}}},
+        {name: "alter rename", query: "ALTER TABLE player RENAME TO player_old", want: [][2]string{{"game", "player"}, {"game", "player_old"}}},
+        {name: "alter rename column", query: "ALTER TABLE log RENAME COLUMN a TO player", want: [][2]string{{"game", "log"}}},
+        {name: "create", query: "CREATE TABLE IF NOT EXISTS `log` (`player` int, `id` int)", want: [][2]string{{"game", "log"}}},
+        {name: "drop", query: "DROP TABLE IF EXISTS `log`, game.`player` /* generated by server */", want: [][2]string{{"game", "log"}, {"game", "player"}}},
+        {name: "rename", query: "RENAME TABLE player TO player_old, log TO other.log", want: [][2]string{{"game", "player"}, {"game", "player_old"}, {"game", "log"}, {"other", "log"}}},
+        {name: "truncate", query: "TRUNCATE player", want: [][2]string{{"game", "player"}}},
+        {name: "truncate table", query: "TRUNCATE TABLE `player`", want: [][2]string{{"game", "player"}}},
+        {name: "create index", query: "CREATE UNIQUE INDEX idx_name ON player (name)", want: [][2]string{{"game", "player"}}},
+        {name: "drop index", query: "DROP INDEX idx_name ON `game`.`player`", want: [][2]string{{"game", "player"}}},
+        {name: "view", query: "CREATE VIEW player_view AS SELECT * FROM player"},
+        {name: "grant", query: "GRANT SELECT ON game.player TO 'u'@'%'"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := getDDLTables(tt.query, "game")
+            if err != nil {
+                t.Fatal(err)
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("getDDLTables() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestIsReplicatedDDL(t *testing.T) {
+    defer func(v string) { db = v }(db)
+    db = "game"
+
+    r := &Replicator{tables: map[string]*replicaTable{"player": {}}}
+    tests := []struct {
+        name string
+        e    *QueryEvent
+        want bool
+    }{
+        {name: "replicated", e: &QueryEvent{Schema: "game", Query: "ALTER TABLE `player` ADD `level` int"}, want: true},
+        {name: "other table", e: &QueryEvent{Schema: "game", Query: "ALTER TABLE `log` ADD `level` int"}},
+        {name: "other schema", e: &QueryEvent{Schema: "other", Query: "ALTER TABLE `player` ADD `level` int"}},
+        {name: "qualified from other schema", e: &QueryEvent{Schema: "other", Query: "ALTER TABLE `game`.`player` ADD `level` int"}, want: true},
+        {name: "rename into", e: &QueryEvent{Schema: "game", Query: "RENAME TABLE player_new TO player"}, want: true},
+        {name: "view", e: &QueryEvent{Schema: "game", Query: "CREATE VIEW v AS SELECT * FROM player"}},
+        {name: "unparsed", e: &QueryEvent{Schema: "game", Query: "ALTER TABLE `player"}, want: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := r.isReplicatedDDL(tt.e); got != tt.want {
+                t.Errorf("isReplicatedDDL() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestGetUnsigned(t *testing.T) {
+    tests := []struct {
+        dataType string
+        v        int64
+        want     any
+    }{
+        {dataType: "TINYINT", v: -1, want: int64(255)},
+        {dataType: "SMALLINT", v: -1, want: int64(65535)},
+        {dataType: "MEDIUMINT", v: -1, want: int64(16777215)},
+        {dataType: "INT", v: -1, want: int64(4294967295)},
+        {dataType: "BIGINT", v: 42, want: int64(42)},
+        {dataType: "BIGINT", v: -1, want: "18446744073709551615"},
+        {dataType: "BIGINT", v: -9223372036854775808, want: "9223372036854775808"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.dataType, func(t *testing.T) {
+            if got := getUnsigned(tt.dataType, tt.v); got != tt.want {
+                t.Errorf("getUnsigned(%s, %d) = %#v, want %#v", tt.dataType, tt.v, got, tt.want)
+            }
+        })
+    }
+}
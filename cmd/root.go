@@ -9,7 +9,8 @@ import (
     "strings"
     "sync"
 
-    "github.com/camry/g/frame/g"
+    "github.com/camry/g/glog"
+    "github.com/camry/g/gutil"
     "github.com/spf13/cobra"
     "gopkg.in/yaml.v3"
     "gorm.io/driver/mysql"
@@ -17,6 +18,37 @@ import (
     "gorm.io/gorm/logger"
 )
 
+const (
+    DecimalReal    = "real"
+    DecimalText    = "text"
+    DecimalInteger = "integer"
+)
+
+const (
+    GeometryWKT     = "wkt"
+    GeometryWKB     = "wkb"
+    GeometryGeoJSON = "geojson"
+)
+
+const (
+    MaskFixed    = "fixed"
+    MaskHash     = "hash"
+    MaskEmail    = "email"
+    MaskName     = "name"
+    MaskPhone    = "phone"
+    MaskTruncate = "truncate"
+    MaskNull     = "null"
+    MaskToken    = "token"
+)
+
+const (
+    CaseSnake = "snake"
+    CaseLower = "lower"
+)
+
+// SnapshotMaxConns 一致性快照最大连接数。
+const SnapshotMaxConns = 16
+
 const (
     Dsn         = "%s:%s@tcp(%s:%d)/information_schema?timeout=10s&parseTime=true&charset=%s"
     HostPattern = "^(.*)\\:(.*)\\@(.*)\\:(\\d+)$"
@@ -30,19 +62,43 @@ func Execute() error {
 func init() {
     cobra.OnInitialize(initConfig)
 
-    rootCmd.Flags().StringVarP(&server, "server", "s", "", "指定服务器。(格式: <user>:<password>@<host>:<port>)")
-    rootCmd.Flags().StringVarP(&db, "db", "d", "", "指定数据库。")
-    rootCmd.Flags().StringVarP(&cfgPath, "config", "c", "", "指定配置文件路径。")
+    rootCmd.PersistentFlags().StringVarP(&server, "server", "s", "", "指定服务器。(格式: <user>:<password>@<host>:<port>)")
+    rootCmd.PersistentFlags().StringVarP(&db, "db", "d", "", "指定数据库。")
+    rootCmd.PersistentFlags().StringVarP(&cfgPath, "config", "c", "", "指定配置文件路径。")
+    rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "指定输出文件路径，*.sql 输出 SQL 文件，其他直接写入 SQLite 数据库。(默认输出 SQL 到标准输出)")
+    rootCmd.PersistentFlags().BoolVar(&noIndex, "no-index", false, "不转换普通索引。(仅保留主键和唯一索引)")
+    rootCmd.PersistentFlags().StringArrayVar(&includes, "include", nil, "只转换匹配的表或字段，可多次指定。(格式: <表>[.<字段>]，支持通配符及 /正则/，exclude 优先)")
+    rootCmd.PersistentFlags().StringArrayVar(&excludes, "exclude", nil, "忽略匹配的表或字段，可多次指定。(格式同 --include，如 log_2024_*、*.*_tmp)")
+    rootCmd.PersistentFlags().StringVar(&decimalMode, "decimal", DecimalReal, "指定 DECIMAL 转换方式: real|text|integer。(integer 按 NUMERIC_SCALE 放大为整数)")
+    rootCmd.Flags().BoolVar(&incremental, "incremental", false, "增量同步到已有 SQLite 数据库: incrementals 配置的表按水位字段只读取变更数据并 ON CONFLICT DO UPDATE 写入。")
+    rootCmd.Flags().BoolVar(&noData, "no-data", false, "只导出表结构，不导出数据。(同 mysqldump -d)")
+    rootCmd.Flags().BoolVar(&noCreate, "no-create", false, "只导出数据，不输出建表语句，写入已有的表结构。(同 mysqldump -t)")
+    rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "只输出转换报告，不导出数据。(同 plan 子命令)")
+    rootCmd.Flags().BoolVar(&singleTransaction, "single-transaction", false, "所有表在同一一致性快照中读取。(需要 RELOAD 权限)")
+    rootCmd.PersistentFlags().Int64Var(&chunkSize, "chunk-size", 1000000, "单表行数超过该值时按主键范围分块并发读取。(0 不分块)")
+    rootCmd.PersistentFlags().IntVar(&chunkWorkers, "chunk-workers", 4, "单表分块并发读取数。")
+    rootCmd.PersistentFlags().StringVar(&geometryFormat, "geometry", GeometryWKT, "指定空间数据转换格式: wkt|wkb|geojson。(保留 SRID)")
+    rootCmd.PersistentFlags().BoolVar(&jsonCheck, "json-check", false, "JSON 字段添加 CHECK (json_valid(...)) 约束。")
+    rootCmd.PersistentFlags().BoolVar(&jsonGenerated, "json-generated", false, "JSON 路径生成列转换为 SQLite json_extract 生成列。(默认按普通字段导出)")
 
-    cobra.CheckErr(rootCmd.MarkFlagRequired("server"))
-    cobra.CheckErr(rootCmd.MarkFlagRequired("db"))
+    cobra.CheckErr(rootCmd.MarkPersistentFlagRequired("server"))
+    cobra.CheckErr(rootCmd.MarkPersistentFlagRequired("db"))
 }
 
 func initConfig() {
 }
 
 type Config struct {
-    Ignores []*IgnoreTable `yaml:"ignores"`
+    Ignores      []*IgnoreTable      `yaml:"ignores"`
+    Includes     []string            `yaml:"includes"`
+    Excludes     []string            `yaml:"excludes"`
+    Decimals     []*DecimalTable     `yaml:"decimals"`
+    Geometries   []*GeometryTable    `yaml:"geometries"`
+    Incrementals []*IncrementalTable `yaml:"incrementals"`
+    Filters      []*FilterTable      `yaml:"filters"`
+    Subset       *SubsetConfig       `yaml:"subset"`
+    Mask         *MaskConfig         `yaml:"mask"`
+    Rename       *RenameConfig       `yaml:"rename"`
 }
 
 type IgnoreTable struct {
@@ -50,132 +106,463 @@ type IgnoreTable struct {
     Columns []string `yaml:"columns"`
 }
 
+type DecimalTable struct {
+    Table   string   `yaml:"table"`
+    Columns []string `yaml:"columns"`
+    Mode    string   `yaml:"mode"`
+}
+
+type GeometryTable struct {
+    Table   string   `yaml:"table"`
+    Columns []string `yaml:"columns"`
+    Format  string   `yaml:"format"`
+}
+
+type IncrementalTable struct {
+    Table  string `yaml:"table"`
+    Column string `yaml:"column"`
+}
+
+type FilterTable struct {
+    Table   string `yaml:"table"`
+    Where   string `yaml:"where"`
+    OrderBy string `yaml:"order_by"`
+    Limit   int64  `yaml:"limit"`
+}
+
+type SubsetConfig struct {
+    Roots     []*FilterTable `yaml:"roots"`
+    Relations []*Relation    `yaml:"relations"`
+}
+
+type MaskConfig struct {
+    Salt  string      `yaml:"salt"`
+    Rules []*MaskRule `yaml:"rules"`
+}
+
+type MaskRule struct {
+    Table   string   `yaml:"table"`
+    Columns []string `yaml:"columns"`
+    Rule    string   `yaml:"rule"`
+    Value   string   `yaml:"value"`
+    Length  int      `yaml:"length"`
+}
+
+type RenameConfig struct {
+    StripPrefix string         `yaml:"strip_prefix"`
+    Case        string         `yaml:"case"`
+    Tables      []*RenameTable `yaml:"tables"`
+}
+
+type RenameTable struct {
+    Table   string            `yaml:"table"`
+    Name    string            `yaml:"name"`
+    Columns map[string]string `yaml:"columns"`
+    Indexes map[string]string `yaml:"indexes"`
+    pattern *namePattern
+}
+
+type Relation struct {
+    Table             string   `yaml:"table"`
+    Columns           []string `yaml:"columns"`
+    ReferencedTable   string   `yaml:"referenced_table"`
+    ReferencedColumns []string `yaml:"referenced_columns"`
+}
+
 var (
     wg   sync.WaitGroup
     lock sync.Mutex
     ch   = make(chan bool, 16)
 
-    server        string
-    db            string
-    cfgPath       string
-    existIndexMap = make(map[string]*int32, 10)
-    sqlTableNames []string
-    sqlTableMap   = make(g.MapStrStr, 100)
+    server            string
+    db                string
+    cfgPath           string
+    output            string
+    noIndex           bool
+    decimalMode       string
+    geometryFormat    string
+    chunkSize         int64
+    chunkWorkers      int
+    singleTransaction bool
+    incremental       bool
+    jsonCheck         bool
+    jsonGenerated     bool
+    serverID          uint32
+    dryRun            bool
+    noData            bool
+    noCreate          bool
+    includes          []string
+    excludes          []string
+    includePatterns   []*Pattern
+    excludePatterns   []*Pattern
+    decimalTables     []*DecimalTable
+    geometryTables    []*GeometryTable
+    icMap             = make(map[string]*IgnoreTable, 10)
+    incrementalMap    = make(map[string]*IncrementalTable, 10)
+    filterMap         = make(map[string]*FilterTable, 10)
+    subsetConfig      *SubsetConfig
+    subsetRows        map[string][]map[string]any
+    maskSalt          string
+    maskRules         []*MaskRule
+    renameConfig      *RenameConfig
+    tableNameMap      = make(map[string]string, 10)
+    watermarks        map[string]string
+    indexTables       = make(map[string]map[string]bool, 10)
+    sqlTableNames     []string
+    failedViews       []string
+    sqlTableMap       = make(map[string]*streamWriter, 100)
+    snapshot          *Snapshot
 
     rootCmd = &cobra.Command{
         Use:     "mysql2sqlite",
         Short:   "MySQL convert to SQLite3.",
         Version: "v1.0.1",
         Run: func(cmd *cobra.Command, args []string) {
-            serverMatched, err1 := regexp.MatchString(HostPattern, server)
-            dbMatched, err2 := regexp.MatchString(DbPattern, db)
-            cobra.CheckErr(err1)
-            cobra.CheckErr(err2)
-            if !serverMatched {
-                cobra.CheckErr(fmt.Errorf("服务器 `%s` 格式错误。(正确格式: <user>:<password>@<host>:<port>)", server))
+            checkFlags()
+            if incremental && (output == "" || strings.HasSuffix(strings.ToLower(output), ".sql")) {
+                cobra.CheckErr(fmt.Errorf("增量同步仅支持直接写入 SQLite 数据库。(--output <*.db>)"))
             }
-            if !dbMatched {
-                cobra.CheckErr(fmt.Errorf("数据库 `%s` 格式错误。", db))
+            if noData && noCreate {
+                cobra.CheckErr(fmt.Errorf("--no-data 与 --no-create 不能同时使用。"))
             }
 
-            var (
-                serverUser = strings.Split(server[0:strings.LastIndex(server, "@")], ":")
-                serverHost = strings.Split(server[strings.LastIndex(server, "@")+1:], ":")
-                err        error
-            )
-            serverDbConfig := &DbConfig{
-                User:     serverUser[0],
-                Password: serverUser[1],
-                Host:     serverHost[0],
-                Charset:  "utf8",
-                Database: db,
-            }
-            serverDbConfig.Port, err = strconv.Atoi(serverHost[1])
-            cobra.CheckErr(err)
-
-            serverDb, err := gorm.Open(mysql.New(mysql.Config{
-                DSN: fmt.Sprintf(Dsn,
-                    serverDbConfig.User, serverDbConfig.Password,
-                    serverDbConfig.Host, serverDbConfig.Port,
-                    serverDbConfig.Charset,
-                ),
-            }), &gorm.Config{
-                SkipDefaultTransaction: true,
-                DisableAutomaticPing:   true,
-                Logger:                 logger.Default.LogMode(logger.Silent),
-            })
-            cobra.CheckErr(err)
-
-            var serverSchema Schema
-            serverSchemaResult := serverDb.Table("SCHEMATA").Limit(1).Find(
-                &serverSchema,
-                "`SCHEMA_NAME` = ?", serverDbConfig.Database,
-            )
-            if serverSchemaResult.RowsAffected <= 0 {
-                cobra.CheckErr(fmt.Errorf("数据库 `%s` 不存在。", serverDbConfig.Database))
+            serverDbConfig, serverDb, serverTableData := openServerDb()
+            loadConfig()
+            cobra.CheckErr(renameTables(serverTableData))
+            if subsetConfig != nil && incremental {
+                cobra.CheckErr(fmt.Errorf("数据子集不能与 --incremental 同时使用。"))
             }
 
-            var serverTableData []*Table
-            serverTableResult := serverDb.Table("TABLES").Order("`TABLE_NAME` ASC").Find(
-                &serverTableData,
-                "`TABLE_SCHEMA` = ?", serverDbConfig.Database,
-            )
-            if serverTableResult.RowsAffected <= 0 {
-                cobra.CheckErr(fmt.Errorf("数据库 `%s` 没有表。", serverDbConfig.Database))
+            if dryRun {
+                cobra.CheckErr(plan(os.Stdout, serverDbConfig, serverDb, serverTableData))
+                return
             }
 
-            // Load Ignore Config
-            icMap := make(map[string]*IgnoreTable, 10)
-            if cfgPath != "" {
-                var ic *Config
-                bytes, err := os.ReadFile(cfgPath)
+            // Output ...
+            var out Output
+            if output == "" {
+                out = NewSqlWriter(os.Stdout)
+            } else if strings.HasSuffix(strings.ToLower(output), ".sql") {
+                f, err := os.Create(output)
                 cobra.CheckErr(err)
-                err = yaml.Unmarshal(bytes, &ic)
+                defer f.Close()
+                out = NewSqlWriter(f)
+            } else {
+                sqliteWriter, err := NewSQLiteWriter(output)
                 cobra.CheckErr(err)
-
-                for _, vv := range ic.Ignores {
-                    icMap[vv.Table] = vv
+                if incremental {
+                    watermarks, err = sqliteWriter.Watermarks()
+                    cobra.CheckErr(err)
+                    // 保留的表已有的索引名不能再分配给重建的表。
+                    indexNames, err := sqliteWriter.IndexTables()
+                    cobra.CheckErr(err)
+                    for indexName, tableName := range indexNames {
+                        addIndexTable(indexName, tableName)
+                    }
                 }
+                out = sqliteWriter
             }
 
-            defer close(ch)
-            for _, serverTable := range serverTableData {
-                var ignoreTable = &IgnoreTable{}
-                if v, ok := icMap[serverTable.TableName]; ok {
-                    ignoreTable = v
-                }
-                isContinue := true
-                if ignoreTable.Table == serverTable.TableName && len(ignoreTable.Columns) == 0 {
-                    isContinue = false
-                }
-                if isContinue {
-                    wg.Add(1)
-                    go NewConverter(serverDbConfig, serverDb, serverTable, ignoreTable).Start()
-                }
+            if singleTransaction {
+                var err error
+                snapshot, err = NewSnapshot(serverDb, limitSnapshotSize(), false)
+                cobra.CheckErr(err)
+                defer snapshot.Close()
             }
-            wg.Wait()
-
-            // Print SQL ...
-            if len(sqlTableNames) > 0 && len(sqlTableMap) > 0 {
-                fmt.Println("PRAGMA foreign_keys = false;")
-                fmt.Println()
-
-                sort.Strings(sqlTableNames)
-
-                for k, sqlTableName := range sqlTableNames {
-                    if sql, ok := sqlTableMap[sqlTableName]; ok {
-                        if k < len(sqlTableMap)-1 {
-                            fmt.Println(sql)
-                            fmt.Println()
-                        } else {
-                            fmt.Println(sql)
-                        }
-                    }
-                }
 
-                fmt.Println()
-                fmt.Println("PRAGMA foreign_keys = true;")
+            if subsetConfig != nil && !noData {
+                cobra.CheckErr(buildSubset(serverDbConfig, serverDb, serverTableData))
             }
+
+            convert(out, serverDbConfig, serverDb, serverTableData)
         },
     }
 )
+
+// checkFlags 校验命令行参数。
+func checkFlags() {
+    serverMatched, err1 := regexp.MatchString(HostPattern, server)
+    dbMatched, err2 := regexp.MatchString(DbPattern, db)
+    cobra.CheckErr(err1)
+    cobra.CheckErr(err2)
+    if !serverMatched {
+        cobra.CheckErr(fmt.Errorf("服务器 `%s` 格式错误。(正确格式: <user>:<password>@<host>:<port>)", server))
+    }
+    if !dbMatched {
+        cobra.CheckErr(fmt.Errorf("数据库 `%s` 格式错误。", db))
+    }
+    if !gutil.InArray(decimalMode, []string{DecimalReal, DecimalText, DecimalInteger}) {
+        cobra.CheckErr(fmt.Errorf("DECIMAL 转换方式 `%s` 错误。(可选: real|text|integer)", decimalMode))
+    }
+    if chunkWorkers < 1 {
+        cobra.CheckErr(fmt.Errorf("分块并发读取数 `%d` 错误。(至少为 1)", chunkWorkers))
+    }
+    if !gutil.InArray(geometryFormat, []string{GeometryWKT, GeometryWKB, GeometryGeoJSON}) {
+        cobra.CheckErr(fmt.Errorf("空间数据转换格式 `%s` 错误。(可选: wkt|wkb|geojson)", geometryFormat))
+    }
+
+    var err error
+    includePatterns, err = parsePatterns(includePatterns, includes)
+    cobra.CheckErr(err)
+    excludePatterns, err = parsePatterns(excludePatterns, excludes)
+    cobra.CheckErr(err)
+}
+
+// openServerDb 连接 MySQL 服务器，读取数据库的表。
+func openServerDb() (*DbConfig, *gorm.DB, []*Table) {
+    var (
+        serverUser = strings.Split(server[0:strings.LastIndex(server, "@")], ":")
+        serverHost = strings.Split(server[strings.LastIndex(server, "@")+1:], ":")
+        err        error
+    )
+    serverDbConfig := &DbConfig{
+        User:     serverUser[0],
+        Password: serverUser[1],
+        Host:     serverHost[0],
+        Charset:  "utf8",
+        Database: db,
+    }
+    serverDbConfig.Port, err = strconv.Atoi(serverHost[1])
+    cobra.CheckErr(err)
+
+    serverDb, err := gorm.Open(mysql.New(mysql.Config{
+        DSN: fmt.Sprintf(Dsn,
+            serverDbConfig.User, serverDbConfig.Password,
+            serverDbConfig.Host, serverDbConfig.Port,
+            serverDbConfig.Charset,
+        ),
+    }), &gorm.Config{
+        SkipDefaultTransaction: true,
+        DisableAutomaticPing:   true,
+        Logger:                 logger.Default.LogMode(logger.Silent),
+    })
+    cobra.CheckErr(err)
+
+    var serverSchema Schema
+    serverSchemaResult := serverDb.Table("SCHEMATA").Limit(1).Find(
+        &serverSchema,
+        "`SCHEMA_NAME` = ?", serverDbConfig.Database,
+    )
+    if serverSchemaResult.RowsAffected <= 0 {
+        cobra.CheckErr(fmt.Errorf("数据库 `%s` 不存在。", serverDbConfig.Database))
+    }
+
+    var serverTableData []*Table
+    serverTableResult := serverDb.Table("TABLES").Order("`TABLE_NAME` ASC").Find(
+        &serverTableData,
+        "`TABLE_SCHEMA` = ?", serverDbConfig.Database,
+    )
+    if serverTableResult.RowsAffected <= 0 {
+        cobra.CheckErr(fmt.Errorf("数据库 `%s` 没有表。", serverDbConfig.Database))
+    }
+
+    return serverDbConfig, serverDb, serverTableData
+}
+
+// loadConfig 加载配置文件。
+func loadConfig() {
+    if cfgPath == "" {
+        return
+    }
+
+    var ic *Config
+    bytes, err := os.ReadFile(cfgPath)
+    cobra.CheckErr(err)
+    err = yaml.Unmarshal(bytes, &ic)
+    cobra.CheckErr(err)
+
+    for _, vv := range ic.Ignores {
+        icMap[vv.Table] = vv
+    }
+
+    includePatterns, err = parsePatterns(includePatterns, ic.Includes)
+    cobra.CheckErr(err)
+    excludePatterns, err = parsePatterns(excludePatterns, ic.Excludes)
+    cobra.CheckErr(err)
+
+    for _, vv := range ic.Decimals {
+        if !gutil.InArray(vv.Mode, []string{DecimalReal, DecimalText, DecimalInteger}) {
+            cobra.CheckErr(fmt.Errorf("表 `%s` DECIMAL 转换方式 `%s` 错误。(可选: real|text|integer)", vv.Table, vv.Mode))
+        }
+    }
+    decimalTables = ic.Decimals
+
+    for _, vv := range ic.Geometries {
+        if !gutil.InArray(vv.Format, []string{GeometryWKT, GeometryWKB, GeometryGeoJSON}) {
+            cobra.CheckErr(fmt.Errorf("表 `%s` 空间数据转换格式 `%s` 错误。(可选: wkt|wkb|geojson)", vv.Table, vv.Format))
+        }
+    }
+    geometryTables = ic.Geometries
+
+    for _, vv := range ic.Incrementals {
+        if vv.Column == "" {
+            cobra.CheckErr(fmt.Errorf("表 `%s` 未指定增量同步水位字段。", vv.Table))
+        }
+        incrementalMap[vv.Table] = vv
+    }
+
+    for _, vv := range ic.Filters {
+        if vv.Limit < 0 {
+            cobra.CheckErr(fmt.Errorf("表 `%s` 过滤行数 `%d` 错误。", vv.Table, vv.Limit))
+        }
+        filterMap[vv.Table] = vv
+    }
+
+    if ic.Subset != nil && len(ic.Subset.Roots) > 0 {
+        for _, vv := range ic.Subset.Relations {
+            if len(vv.Columns) == 0 || len(vv.Columns) != len(vv.ReferencedColumns) {
+                cobra.CheckErr(fmt.Errorf("表 `%s` 关联 `%s` 字段数不一致。", vv.Table, vv.ReferencedTable))
+            }
+        }
+        subsetConfig = ic.Subset
+    }
+
+    if ic.Mask != nil {
+        for _, vv := range ic.Mask.Rules {
+            if len(vv.Columns) == 0 {
+                cobra.CheckErr(fmt.Errorf("表 `%s` 脱敏规则未指定字段。", vv.Table))
+            }
+            if !gutil.InArray(vv.Rule, []string{MaskFixed, MaskHash, MaskEmail, MaskName, MaskPhone, MaskTruncate, MaskNull, MaskToken}) {
+                cobra.CheckErr(fmt.Errorf("表 `%s` 脱敏规则 `%s` 错误。(可选: fixed|hash|email|name|phone|truncate|null|token)", vv.Table, vv.Rule))
+            }
+            if vv.Rule == MaskTruncate && vv.Length <= 0 {
+                cobra.CheckErr(fmt.Errorf("表 `%s` 脱敏规则 truncate 未指定保留长度。", vv.Table))
+            }
+        }
+        maskSalt, maskRules = ic.Mask.Salt, ic.Mask.Rules
+    }
+
+    if ic.Rename != nil {
+        if ic.Rename.Case != "" && !gutil.InArray(ic.Rename.Case, []string{CaseSnake, CaseLower}) {
+            cobra.CheckErr(fmt.Errorf("重命名大小写转换 `%s` 错误。(可选: snake|lower)", ic.Rename.Case))
+        }
+        for _, vv := range ic.Rename.Tables {
+            vv.pattern, err = parseNamePattern(vv.Table)
+            if err != nil {
+                cobra.CheckErr(fmt.Errorf("表 `%s` 重命名规则错误: %w", vv.Table, err))
+            }
+        }
+        renameConfig = ic.Rename
+    }
+}
+
+// getIgnoreTable 表的忽略配置，整表忽略（含 include/exclude）返回 nil。
+func getIgnoreTable(tableName string) *IgnoreTable {
+    if isIgnoreTable(tableName) {
+        return nil
+    }
+    if v, ok := icMap[tableName]; ok {
+        return v
+    }
+    return &IgnoreTable{}
+}
+
+// loadIndexTables 读取各 SQLite 索引名所属的表，用于同名索引加表名前缀。
+func loadIndexTables(serverDbConfig *DbConfig, serverDb *gorm.DB, serverTableData []*Table) error {
+    var serverStatisticsData []Statistic
+    err := serverDb.Table("STATISTICS").Find(
+        &serverStatisticsData,
+        "`TABLE_SCHEMA` = ? AND `INDEX_NAME` <> 'PRIMARY'", serverDbConfig.Database,
+    ).Error
+    if err != nil {
+        return err
+    }
+
+    tableNames := make(map[string]bool, len(serverTableData))
+    for _, serverTable := range serverTableData {
+        if serverTable.TableType == "BASE TABLE" && getIgnoreTable(serverTable.TableName) != nil {
+            tableNames[serverTable.TableName] = true
+        }
+    }
+    for _, serverStatistic := range serverStatisticsData {
+        if tableNames[serverStatistic.TableName] {
+            addIndexTable(renameIndex(serverStatistic.TableName, serverStatistic.IndexName), renameTable(serverStatistic.TableName))
+        }
+    }
+    return nil
+}
+
+// addIndexTable 记录索引名所属的表（不区分大小写）。
+func addIndexTable(indexName, tableName string) {
+    indexName = strings.ToLower(indexName)
+    if indexTables[indexName] == nil {
+        indexTables[indexName] = make(map[string]bool)
+    }
+    indexTables[indexName][strings.ToLower(tableName)] = true
+}
+
+// limitSnapshotSize 一致性快照连接数: 每个并发表读取（含分块）各占一个快照连接，总数不超过 SnapshotMaxConns。
+// 超出时减少单表分块并发数、并发表数，保证并发读取的表均能取得所需连接，不会互相等待。
+func limitSnapshotSize() int {
+    workers := 1
+    if chunkSize > 0 {
+        if chunkWorkers > SnapshotMaxConns {
+            chunkWorkers = SnapshotMaxConns
+        }
+        workers = chunkWorkers
+    }
+    if tables := SnapshotMaxConns / workers; cap(ch) > tables {
+        ch = make(chan bool, tables)
+    }
+    return cap(ch) * workers
+}
+
+// convert 并发转换全部表并按表名顺序输出，返回各表转换器。
+func convert(out Output, serverDbConfig *DbConfig, serverDb *gorm.DB, serverTableData []*Table) map[string]*Converter {
+    var sqlViewNames []string
+    if err := loadIndexTables(serverDbConfig, serverDb, serverTableData); err != nil {
+        glog.Fatal(err)
+    }
+    converterMap := make(map[string]*Converter, len(serverTableData))
+    for _, serverTable := range serverTableData {
+        ignoreTable := getIgnoreTable(serverTable.TableName)
+        if ignoreTable == nil {
+            continue
+        }
+        writer := newStreamWriter()
+        if serverTable.TableType == "VIEW" {
+            sqlViewNames = append(sqlViewNames, serverTable.TableName)
+        } else {
+            sqlTableNames = append(sqlTableNames, serverTable.TableName)
+        }
+        sqlTableMap[serverTable.TableName] = writer
+        converterMap[serverTable.TableName] = NewConverter(serverDbConfig, serverDb, serverTable, ignoreTable, writer)
+    }
+
+    // 视图在全部基表之后输出。
+    sort.Strings(sqlTableNames)
+    sort.Strings(sqlViewNames)
+    sqlTableNames = append(sqlTableNames, sqlViewNames...)
+
+    // 按表名顺序占用并发槽位，保证当前输出的表已在转换中。
+    wg.Add(len(sqlTableNames))
+    go func() {
+        for _, sqlTableName := range sqlTableNames {
+            ch <- true
+            go func(converter *Converter, writer *streamWriter) {
+                defer writer.close()
+                converter.Start()
+            }(converterMap[sqlTableName], sqlTableMap[sqlTableName])
+        }
+    }()
+
+    cobra.CheckErr(out.Begin())
+
+    for _, sqlTableName := range sqlTableNames {
+        cobra.CheckErr(sqlTableMap[sqlTableName].flush(out))
+    }
+
+    cobra.CheckErr(out.End())
+
+    wg.Wait()
+
+    if len(failedViews) > 0 {
+        sort.Strings(failedViews)
+        glog.Warnf("以下 %d 个视图无法转换:", len(failedViews))
+        for _, failedView := range failedViews {
+            glog.Warnf("  %s", failedView)
+        }
+    }
+
+    return converterMap
+}
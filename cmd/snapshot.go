@@ -0,0 +1,127 @@
+package cmd
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+
+    "gorm.io/gorm"
+)
+
+// Snapshot 一致性快照连接池。
+// 与 mysqldump/mydumper 相同: 持有全局读锁期间，所有连接开启 START TRANSACTION WITH CONSISTENT SNAPSHOT，
+// 随后释放全局读锁，各连接读取同一时间点的数据。
+type Snapshot struct {
+    conns    []*sql.Conn
+    sessions chan *gorm.DB
+
+    // BinlogFile、BinlogPosition 快照对应的 binlog 位置。
+    BinlogFile     string
+    BinlogPosition uint32
+}
+
+// NewSnapshot 新建 size 个共享同一快照的连接，position 为 true 时同时记录 binlog 位置。
+func NewSnapshot(db *gorm.DB, size int, position bool) (*Snapshot, error) {
+    ctx := context.Background()
+
+    sqlDb, err := db.DB()
+    if err != nil {
+        return nil, err
+    }
+
+    lockConn, err := sqlDb.Conn(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer lockConn.Close()
+
+    if _, err = lockConn.ExecContext(ctx, "FLUSH TABLES WITH READ LOCK"); err != nil {
+        return nil, fmt.Errorf("全局读锁失败，请检查 RELOAD 权限: %w", err)
+    }
+    // 快照全部开启（或失败）后释放全局读锁。
+    defer lockConn.ExecContext(ctx, "UNLOCK TABLES")
+
+    s := &Snapshot{sessions: make(chan *gorm.DB, size)}
+    if position {
+        if s.BinlogFile, s.BinlogPosition, err = getBinlogPosition(ctx, lockConn); err != nil {
+            return nil, err
+        }
+    }
+
+    for i := 0; i < size; i++ {
+        conn, err := sqlDb.Conn(ctx)
+        if err != nil {
+            s.Close()
+            return nil, err
+        }
+        s.conns = append(s.conns, conn)
+
+        for _, statement := range []string{
+            "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+            "START TRANSACTION WITH CONSISTENT SNAPSHOT",
+        } {
+            if _, err = conn.ExecContext(ctx, statement); err != nil {
+                s.Close()
+                return nil, fmt.Errorf("开启一致性快照失败: %w", err)
+            }
+        }
+
+        session := db.Session(&gorm.Session{NewDB: true, Context: ctx})
+        session.Statement.ConnPool = conn
+        s.sessions <- session
+    }
+
+    return s, nil
+}
+
+// getBinlogPosition 当前 binlog 位置。(MySQL 8.4 起为 SHOW BINARY LOG STATUS)
+func getBinlogPosition(ctx context.Context, conn *sql.Conn) (string, uint32, error) {
+    var (
+        file     string
+        position uint32
+    )
+
+    for _, statement := range []string{"SHOW MASTER STATUS", "SHOW BINARY LOG STATUS"} {
+        rows, err := conn.QueryContext(ctx, statement)
+        if err != nil {
+            continue
+        }
+        columns, err := rows.Columns()
+        if err != nil {
+            rows.Close()
+            return "", 0, err
+        }
+        if !rows.Next() {
+            rows.Close()
+            return "", 0, fmt.Errorf("无法读取 binlog 位置，请检查是否开启 binlog")
+        }
+        values := make([]any, len(columns))
+        values[0], values[1] = &file, &position
+        for i := 2; i < len(columns); i++ {
+            values[i] = new(sql.RawBytes)
+        }
+        err = rows.Scan(values...)
+        rows.Close()
+        return file, position, err
+    }
+    return "", 0, fmt.Errorf("无法读取 binlog 位置，请检查 REPLICATION CLIENT 权限")
+}
+
+// Acquire 获取快照连接，无空闲连接时等待。
+func (s *Snapshot) Acquire() *gorm.DB {
+    return <-s.sessions
+}
+
+// Release 归还快照连接。
+func (s *Snapshot) Release(session *gorm.DB) {
+    s.sessions <- session
+}
+
+// Close 结束快照事务并关闭连接。
+func (s *Snapshot) Close() {
+    for _, conn := range s.conns {
+        _, _ = conn.ExecContext(context.Background(), "ROLLBACK")
+        _ = conn.Close()
+    }
+    s.conns = nil
+}
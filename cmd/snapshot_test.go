@@ -0,0 +1,71 @@
+package cmd
+
+import (
+    "testing"
+
+    "gorm.io/gorm"
+)
+
+func TestAcquireDb(t *testing.T) {
+    defer func(s *Snapshot) {
+        snapshot = s
+    }(snapshot)
+
+    serverDb, session := &gorm.DB{}, &gorm.DB{}
+    c := &Converter{serverDb: serverDb}
+
+    snapshot = nil
+    db, release := c.acquireDb()
+    if db != serverDb {
+        t.Fatal("未开启快照时应使用 serverDb")
+    }
+    release()
+
+    snapshot = &Snapshot{sessions: make(chan *gorm.DB, 1)}
+    snapshot.sessions <- session
+    db, release = c.acquireDb()
+    if db != session {
+        t.Fatal("开启快照时应使用快照连接")
+    }
+    if len(snapshot.sessions) != 0 {
+        t.Fatal("快照连接被占用时不应留在连接池")
+    }
+    release()
+    if len(snapshot.sessions) != 1 {
+        t.Fatal("快照连接未归还")
+    }
+}
+
+func TestLimitSnapshotSize(t *testing.T) {
+    defer func(c chan bool, size int64, workers int) {
+        ch, chunkSize, chunkWorkers = c, size, workers
+    }(ch, chunkSize, chunkWorkers)
+
+    tests := []struct {
+        name        string
+        tables      int
+        size        int64
+        workers     int
+        want        int
+        wantTables  int
+        wantWorkers int
+    }{
+        {name: "no chunk", tables: 16, workers: 4, want: 16, wantTables: 16, wantWorkers: 4},
+        {name: "chunk", tables: 16, size: 1000, workers: 4, want: 16, wantTables: 4, wantWorkers: 4},
+        {name: "chunk remainder", tables: 16, size: 1000, workers: 5, want: 15, wantTables: 3, wantWorkers: 5},
+        {name: "few tables", tables: 2, size: 1000, workers: 4, want: 8, wantTables: 2, wantWorkers: 4},
+        {name: "too many workers", tables: 16, size: 1000, workers: 64, want: 16, wantTables: 1, wantWorkers: 16},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ch, chunkSize, chunkWorkers = make(chan bool, tt.tables), tt.size, tt.workers
+            if got := limitSnapshotSize(); got != tt.want {
+                t.Errorf("limitSnapshotSize() = %d, want %d", got, tt.want)
+            }
+            if cap(ch) != tt.wantTables || chunkWorkers != tt.wantWorkers {
+                t.Errorf("tables = %d, workers = %d, want %d, %d", cap(ch), chunkWorkers, tt.wantTables, tt.wantWorkers)
+            }
+        })
+    }
+}
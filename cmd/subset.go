@@ -0,0 +1,396 @@
+package cmd
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+
+    "github.com/camry/g/glog"
+    "github.com/camry/g/gutil"
+    "gorm.io/gorm"
+)
+
+// Subset 引用一致的数据子集: 从根表行出发沿外键（及配置的逻辑关联）遍历，
+// 所有收集到的行向上补齐被引用的父表行，根表行及其子孙行向下收集引用它们的子表行。
+// 父表行不再向下遍历，避免经公共父表（如区服、配置表）扩散到整库。
+type Subset struct {
+    serverDbConfig *DbConfig
+    serverDb       *gorm.DB
+    relations      []*Relation
+    tables         map[string]*subsetTable
+    tasks          []*subsetTask
+}
+
+// subsetTable 子集中的单表。
+type subsetTable struct {
+    keys   []string // 主键，没有主键时按整行去重
+    rows   []map[string]any
+    seen   map[string]bool // 已收集的行
+    walked map[string]bool // 已向下遍历的行
+}
+
+// subsetTask 待遍历的行。
+type subsetTask struct {
+    tableName string
+    rows      []map[string]any
+    down      bool
+}
+
+// buildSubset 按配置文件 subset 计算数据子集，--single-transaction 时在一致性快照中读取。
+func buildSubset(serverDbConfig *DbConfig, serverDb *gorm.DB, serverTableData []*Table) error {
+    if snapshot != nil {
+        session := snapshot.Acquire()
+        defer snapshot.Release(session)
+        serverDb = session
+    }
+
+    s, err := NewSubset(serverDbConfig, serverDb, serverTableData, subsetConfig.Relations)
+    if err != nil {
+        return err
+    }
+    subsetRows, err = s.Build(subsetConfig.Roots)
+    return err
+}
+
+// NewSubset 新建数据子集，读取各表主键及外键关联。
+func NewSubset(serverDbConfig *DbConfig, serverDb *gorm.DB, serverTableData []*Table, relations []*Relation) (*Subset, error) {
+    s := &Subset{
+        serverDbConfig: serverDbConfig,
+        serverDb:       serverDb,
+        tables:         make(map[string]*subsetTable, len(serverTableData)),
+    }
+
+    for _, serverTable := range serverTableData {
+        if serverTable.TableType != "BASE TABLE" || getIgnoreTable(serverTable.TableName) == nil {
+            continue
+        }
+
+        var serverStatisticsData []Statistic
+        err := serverDb.Table("STATISTICS").Order("`SEQ_IN_INDEX` ASC").Find(
+            &serverStatisticsData,
+            "`TABLE_SCHEMA` = ? AND `TABLE_NAME` = ? AND `INDEX_NAME` = 'PRIMARY'",
+            serverDbConfig.Database, serverTable.TableName,
+        ).Error
+        if err != nil {
+            return nil, err
+        }
+
+        t := &subsetTable{seen: make(map[string]bool), walked: make(map[string]bool)}
+        for _, serverStatistic := range serverStatisticsData {
+            t.keys = append(t.keys, serverStatistic.ColumnName)
+        }
+        s.tables[serverTable.TableName] = t
+    }
+
+    foreignKeys, err := s.getForeignKeys()
+    if err != nil {
+        return nil, err
+    }
+    for _, relation := range foreignKeys {
+        if s.isValidRelation(relation) {
+            s.relations = append(s.relations, relation)
+        }
+    }
+    for _, relation := range relations {
+        if err = s.checkRelation(serverTableData, relation); err != nil {
+            return nil, err
+        }
+        if !s.isValidRelation(relation) {
+            glog.Warnf("表 `%s` 关联 `%s` 涉及已忽略的表或字段，跳过该关联。", relation.Table, relation.ReferencedTable)
+            continue
+        }
+        s.relations = append(s.relations, relation)
+    }
+    return s, nil
+}
+
+// checkRelation 校验配置的逻辑关联两端的表及字段存在。
+func (s *Subset) checkRelation(serverTableData []*Table, relation *Relation) error {
+    for _, side := range []struct {
+        table   string
+        columns []string
+    }{
+        {relation.Table, relation.Columns},
+        {relation.ReferencedTable, relation.ReferencedColumns},
+    } {
+        exists := false
+        for _, serverTable := range serverTableData {
+            if serverTable.TableName == side.table && serverTable.TableType == "BASE TABLE" {
+                exists = true
+            }
+        }
+        if !exists {
+            return fmt.Errorf("表 `%s` 关联 `%s` 的表 `%s` 不存在。", relation.Table, relation.ReferencedTable, side.table)
+        }
+
+        var columnNames []string
+        err := s.serverDb.Table("COLUMNS").Where(
+            "`TABLE_SCHEMA` = ? AND `TABLE_NAME` = ?", s.serverDbConfig.Database, side.table,
+        ).Pluck("COLUMN_NAME", &columnNames).Error
+        if err != nil {
+            return err
+        }
+        for _, column := range side.columns {
+            if !gutil.InArray(column, columnNames) {
+                return fmt.Errorf("表 `%s` 关联 `%s` 的字段 `%s`.`%s` 不存在。", relation.Table, relation.ReferencedTable, side.table, column)
+            }
+        }
+    }
+    return nil
+}
+
+// getForeignKeys 数据库内的外键关联。
+func (s *Subset) getForeignKeys() ([]*Relation, error) {
+    var (
+        relations                    []*Relation
+        serverReferentialConstraints []ReferentialConstraints
+        serverKeyColumnUsageData     []KeyColumnUsage
+    )
+
+    err := s.serverDb.Table("REFERENTIAL_CONSTRAINTS").Order("`TABLE_NAME` ASC, `CONSTRAINT_NAME` ASC").Find(
+        &serverReferentialConstraints,
+        "`CONSTRAINT_SCHEMA` = ?", s.serverDbConfig.Database,
+    ).Error
+    if err != nil || len(serverReferentialConstraints) == 0 {
+        return nil, err
+    }
+
+    err = s.serverDb.Table("KEY_COLUMN_USAGE").Order("`CONSTRAINT_NAME` ASC, `ORDINAL_POSITION` ASC").Find(
+        &serverKeyColumnUsageData,
+        "`TABLE_SCHEMA` = ? AND `REFERENCED_TABLE_NAME` IS NOT NULL",
+        s.serverDbConfig.Database,
+    ).Error
+    if err != nil {
+        return nil, err
+    }
+
+    for _, serverReferential := range serverReferentialConstraints {
+        relation := &Relation{Table: serverReferential.TableName, ReferencedTable: serverReferential.ReferencedTableName}
+        for _, serverKeyColumnUsage := range serverKeyColumnUsageData {
+            if serverKeyColumnUsage.TableName != serverReferential.TableName ||
+                serverKeyColumnUsage.ConstraintName != serverReferential.ConstraintName ||
+                serverKeyColumnUsage.ReferencedTableSchema != s.serverDbConfig.Database {
+                continue
+            }
+            relation.Columns = append(relation.Columns, serverKeyColumnUsage.ColumnName)
+            relation.ReferencedColumns = append(relation.ReferencedColumns, serverKeyColumnUsage.ReferencedColumnName)
+        }
+        if len(relation.Columns) > 0 {
+            relations = append(relations, relation)
+        }
+    }
+    return relations, nil
+}
+
+// isValidRelation 关联两端的表及字段均未忽略。
+func (s *Subset) isValidRelation(relation *Relation) bool {
+    for _, side := range []struct {
+        table   string
+        columns []string
+    }{
+        {relation.Table, relation.Columns},
+        {relation.ReferencedTable, relation.ReferencedColumns},
+    } {
+        if _, ok := s.tables[side.table]; !ok {
+            return false
+        }
+        for _, column := range side.columns {
+            if isIgnoreColumn(side.table, column) {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+// Build 从根表行出发计算子集，返回各表的行，未涉及的表没有行。
+func (s *Subset) Build(roots []*FilterTable) (map[string][]map[string]any, error) {
+    for _, root := range roots {
+        if _, ok := s.tables[root.Table]; !ok {
+            return nil, fmt.Errorf("子集根表 `%s` 不存在或已忽略。", root.Table)
+        }
+        rows, err := s.fetch(root.Table, func(db *gorm.DB) *gorm.DB {
+            if root.Where != "" {
+                db = db.Where(fmt.Sprintf("(%s)", root.Where))
+            }
+            if root.OrderBy != "" {
+                db = db.Order(root.OrderBy)
+            }
+            if root.Limit > 0 {
+                db = db.Limit(int(root.Limit))
+            }
+            return db
+        })
+        if err != nil {
+            return nil, err
+        }
+        s.add(root.Table, rows, true)
+    }
+
+    for len(s.tasks) > 0 {
+        task := s.tasks[0]
+        s.tasks = s.tasks[1:]
+
+        for _, relation := range s.relations {
+            // 向上: 补齐被引用的父表行。
+            if relation.Table == task.tableName {
+                rows, err := s.fetchIn(relation.ReferencedTable, relation.ReferencedColumns, getRelationValues(task.rows, relation.Columns), "")
+                if err != nil {
+                    return nil, err
+                }
+                s.add(relation.ReferencedTable, rows, false)
+            }
+            // 向下: 收集引用当前行的子表行，子表配置了 filters 时按其 where 过滤。
+            if task.down && relation.ReferencedTable == task.tableName {
+                var where string
+                if filterTable, ok := filterMap[relation.Table]; ok {
+                    where = filterTable.Where
+                }
+                rows, err := s.fetchIn(relation.Table, relation.Columns, getRelationValues(task.rows, relation.ReferencedColumns), where)
+                if err != nil {
+                    return nil, err
+                }
+                s.add(relation.Table, rows, true)
+            }
+        }
+    }
+
+    var tableNames []string
+    subsetRows := make(map[string][]map[string]any, len(s.tables))
+    for tableName, t := range s.tables {
+        subsetRows[tableName] = t.rows
+        tableNames = append(tableNames, tableName)
+    }
+    sort.Strings(tableNames)
+    for _, tableName := range tableNames {
+        if n := len(subsetRows[tableName]); n > 0 {
+            glog.Infof("子集表 `%s` %d 行。", tableName, n)
+        }
+    }
+    return subsetRows, nil
+}
+
+// add 收集行，新行及首次向下遍历的行加入待遍历队列。
+func (s *Subset) add(tableName string, rows []map[string]any, down bool) {
+    t := s.tables[tableName]
+
+    var upRows, downRows []map[string]any
+    for _, row := range rows {
+        key := getRowKey(row, t.keys)
+        if !t.seen[key] {
+            t.seen[key] = true
+            t.rows = append(t.rows, row)
+            upRows = append(upRows, row)
+        }
+        if down && !t.walked[key] {
+            t.walked[key] = true
+            downRows = append(downRows, row)
+        }
+    }
+
+    // 向下遍历的任务同时向上补齐，新行均在其中。
+    if down && len(downRows) > 0 {
+        s.tasks = append(s.tasks, &subsetTask{tableName: tableName, rows: downRows, down: true})
+    } else if !down && len(upRows) > 0 {
+        s.tasks = append(s.tasks, &subsetTask{tableName: tableName, rows: upRows})
+    }
+}
+
+// fetch 读取表数据，按 scope 中的排序及主键排序。
+func (s *Subset) fetch(tableName string, scope func(db *gorm.DB) *gorm.DB) ([]map[string]any, error) {
+    var rows []map[string]any
+
+    // Scopes 在执行时才应用，主键排序放在 scope 之后，不覆盖根表 order_by。
+    query := s.serverDb.Table(fmt.Sprintf("`%s`.`%s`", s.serverDbConfig.Database, tableName)).Scopes(scope, func(db *gorm.DB) *gorm.DB {
+        for _, key := range s.tables[tableName].keys {
+            db = db.Order(fmt.Sprintf("`%s` ASC", key))
+        }
+        return db
+    })
+    if err := query.Find(&rows).Error; err != nil {
+        return nil, fmt.Errorf("子集表 `%s` 读取失败: %w", tableName, err)
+    }
+    return rows, nil
+}
+
+// fetchIn 分批读取 (columns) IN (values) 的行，where 为附加条件。
+func (s *Subset) fetchIn(tableName string, columns []string, values [][]any, where string) ([]map[string]any, error) {
+    var (
+        rows  []map[string]any
+        limit = 500
+    )
+
+    var cs []string
+    for _, column := range columns {
+        cs = append(cs, fmt.Sprintf("`%s`", column))
+    }
+    for start := 0; start < len(values); start += limit {
+        end := start + limit
+        if end > len(values) {
+            end = len(values)
+        }
+
+        var (
+            ps   []string
+            args []any
+        )
+        for _, value := range values[start:end] {
+            ps = append(ps, fmt.Sprintf("(%s)", strings.TrimSuffix(strings.Repeat("?,", len(value)), ",")))
+            args = append(args, value...)
+        }
+        batch, err := s.fetch(tableName, func(db *gorm.DB) *gorm.DB {
+            db = db.Where(fmt.Sprintf("(%s) IN (%s)", strings.Join(cs, ","), strings.Join(ps, ",")), args...)
+            if where != "" {
+                db = db.Where(fmt.Sprintf("(%s)", where))
+            }
+            return db
+        })
+        if err != nil {
+            return nil, err
+        }
+        rows = append(rows, batch...)
+    }
+    return rows, nil
+}
+
+// getRelationValues 行的关联字段值（去重，跳过含 NULL 的值）。
+func getRelationValues(rows []map[string]any, columns []string) [][]any {
+    var values [][]any
+    seen := make(map[string]bool, len(rows))
+    for _, row := range rows {
+        key := getRowKey(row, columns)
+        if seen[key] {
+            continue
+        }
+        seen[key] = true
+
+        value := make([]any, 0, len(columns))
+        for _, column := range columns {
+            if row[column] == nil {
+                value = nil
+                break
+            }
+            value = append(value, row[column])
+        }
+        if value != nil {
+            values = append(values, value)
+        }
+    }
+    return values
+}
+
+// getRowKey 行的去重键，columns 为空时按整行。
+func getRowKey(row map[string]any, columns []string) string {
+    if len(columns) == 0 {
+        for column := range row {
+            columns = append(columns, column)
+        }
+        sort.Strings(columns)
+    }
+
+    var b strings.Builder
+    for _, column := range columns {
+        fmt.Fprintf(&b, "%T:%v\x00", row[column], row[column])
+    }
+    return b.String()
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+    "reflect"
+    "sort"
+    "testing"
+)
+
+func TestSubsetBuild(t *testing.T) {
+    serverDb := newTestServerDb(t,
+        "CREATE TABLE `game`.`server` (`id` INTEGER PRIMARY KEY)",
+        "CREATE TABLE `game`.`player` (`id` INTEGER PRIMARY KEY, `server_id` INTEGER)",
+        "CREATE TABLE `game`.`order` (`id` INTEGER PRIMARY KEY, `player_id` INTEGER)",
+        "CREATE TABLE `game`.`item_log` (`id` INTEGER PRIMARY KEY, `player_id` INTEGER)",
+        "INSERT INTO `game`.`server` VALUES (1), (2)",
+        "INSERT INTO `game`.`player` VALUES (1, 1), (2, 1), (3, 2), (4, NULL)",
+        "INSERT INTO `game`.`order` VALUES (10, 1), (11, 2), (12, 1), (13, 4)",
+        "INSERT INTO `game`.`item_log` VALUES (20, 1), (21, 3)",
+    )
+
+    var statistics []Statistic
+    for _, tableName := range []string{"server", "player", "order", "item_log"} {
+        statistics = append(statistics, Statistic{TableSchema: "game", TableName: tableName, IndexName: "PRIMARY", SeqInIndex: 1, ColumnName: "id"})
+    }
+    for _, err := range []error{
+        serverDb.Table("STATISTICS").Create(statistics).Error,
+        serverDb.Table("COLUMNS").Create([]Column{
+            {TableSchema: "game", TableName: "player", ColumnName: "id", OrdinalPosition: 1},
+            {TableSchema: "game", TableName: "item_log", ColumnName: "id", OrdinalPosition: 1},
+            {TableSchema: "game", TableName: "item_log", ColumnName: "player_id", OrdinalPosition: 2},
+        }).Error,
+        serverDb.Table("REFERENTIAL_CONSTRAINTS").Create([]ReferentialConstraints{
+            {ConstraintSchema: "game", ConstraintName: "fk_player_server", TableName: "player", ReferencedTableName: "server"},
+            {ConstraintSchema: "game", ConstraintName: "fk_order_player", TableName: "order", ReferencedTableName: "player"},
+        }).Error,
+        serverDb.Table("KEY_COLUMN_USAGE").Create([]KeyColumnUsage{
+            {ConstraintName: "fk_player_server", TableSchema: "game", TableName: "player", ColumnName: "server_id", OrdinalPosition: 1, ReferencedTableSchema: "game", ReferencedTableName: "server", ReferencedColumnName: "id"},
+            {ConstraintName: "fk_order_player", TableSchema: "game", TableName: "order", ColumnName: "player_id", OrdinalPosition: 1, ReferencedTableSchema: "game", ReferencedTableName: "player", ReferencedColumnName: "id"},
+        }).Error,
+    } {
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    var serverTableData []*Table
+    for _, tableName := range []string{"item_log", "order", "player", "server"} {
+        serverTableData = append(serverTableData, &Table{TableName: tableName, TableType: "BASE TABLE"})
+    }
+    relations := []*Relation{{Table: "item_log", Columns: []string{"player_id"}, ReferencedTable: "player", ReferencedColumns: []string{"id"}}}
+
+    tests := []struct {
+        name  string
+        roots []*FilterTable
+        want  map[string][]int64
+    }{
+        {
+            // 父表行不再向下遍历: 同区服的玩家 2 不在子集中。
+            name:  "player",
+            roots: []*FilterTable{{Table: "player", Where: "`id` = 1"}},
+            want:  map[string][]int64{"server": {1}, "player": {1}, "order": {10, 12}, "item_log": {20}},
+        },
+        {
+            name:  "order",
+            roots: []*FilterTable{{Table: "order", Where: "`id` = 11"}},
+            want:  map[string][]int64{"server": {1}, "player": {2}, "order": {11}},
+        },
+        {
+            name:  "null foreign key",
+            roots: []*FilterTable{{Table: "player", Where: "`id` = 4"}},
+            want:  map[string][]int64{"player": {4}, "order": {13}},
+        },
+        {
+            name:  "server",
+            roots: []*FilterTable{{Table: "server", Where: "`id` = 2"}},
+            want:  map[string][]int64{"server": {2}, "player": {3}, "item_log": {21}},
+        },
+        {
+            // 根表 order_by 优先于主键排序。
+            name:  "order by",
+            roots: []*FilterTable{{Table: "order", OrderBy: "`id` DESC", Limit: 1}},
+            want:  map[string][]int64{"player": {4}, "order": {13}},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s, err := NewSubset(&DbConfig{Database: "game"}, serverDb, serverTableData, relations)
+            if err != nil {
+                t.Fatal(err)
+            }
+            subsetRows, err := s.Build(tt.roots)
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            got := make(map[string][]int64)
+            for tableName, rows := range subsetRows {
+                for _, row := range rows {
+                    got[tableName] = append(got[tableName], row["id"].(int64))
+                }
+                sort.Slice(got[tableName], func(i, j int) bool { return got[tableName][i] < got[tableName][j] })
+            }
+            if !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Build() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+
+    s, err := NewSubset(&DbConfig{Database: "game"}, serverDb, serverTableData, relations)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err = s.Build([]*FilterTable{{Table: "missing"}}); err == nil {
+        t.Error("根表不存在时应返回错误")
+    }
+
+    for _, relation := range []*Relation{
+        {Table: "missing", Columns: []string{"player_id"}, ReferencedTable: "player", ReferencedColumns: []string{"id"}},
+        {Table: "item_log", Columns: []string{"player_id"}, ReferencedTable: "missing", ReferencedColumns: []string{"id"}},
+        {Table: "item_log", Columns: []string{"role_id"}, ReferencedTable: "player", ReferencedColumns: []string{"id"}},
+        {Table: "item_log", Columns: []string{"player_id"}, ReferencedTable: "player", ReferencedColumns: []string{"uid"}},
+    } {
+        if _, err = NewSubset(&DbConfig{Database: "game"}, serverDb, serverTableData, []*Relation{relation}); err == nil {
+            t.Errorf("关联 %+v 的表或字段不存在时应返回错误", *relation)
+        }
+    }
+}
+
+func TestGetRelationValues(t *testing.T) {
+    rows := []map[string]any{
+        {"id": int64(1), "a": int64(1), "b": "x"},
+        {"id": int64(2), "a": int64(1), "b": "x"},
+        {"id": int64(3), "a": int64(2), "b": nil},
+        {"id": int64(4), "a": int64(2), "b": "y"},
+    }
+
+    tests := []struct {
+        name    string
+        columns []string
+        want    [][]any
+    }{
+        {name: "single", columns: []string{"a"}, want: [][]any{{int64(1)}, {int64(2)}}},
+        {name: "composite", columns: []string{"a", "b"}, want: [][]any{{int64(1), "x"}, {int64(2), "y"}}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := getRelationValues(rows, tt.columns); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("getRelationValues() = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestGetRowKey(t *testing.T) {
+    tests := []struct {
+        name    string
+        a, b    map[string]any
+        columns []string
+        equal   bool
+    }{
+        {name: "same key", a: map[string]any{"id": int64(1), "v": "a"}, b: map[string]any{"id": int64(1), "v": "b"}, columns: []string{"id"}, equal: true},
+        {name: "different key", a: map[string]any{"id": int64(1)}, b: map[string]any{"id": int64(2)}, columns: []string{"id"}},
+        {name: "different type", a: map[string]any{"id": int64(1)}, b: map[string]any{"id": "1"}, columns: []string{"id"}},
+        {name: "whole row", a: map[string]any{"x": int64(1), "y": "a"}, b: map[string]any{"y": "a", "x": int64(1)}, equal: true},
+        {name: "whole row differs", a: map[string]any{"x": int64(1), "y": "a"}, b: map[string]any{"x": int64(1), "y": "b"}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if equal := getRowKey(tt.a, tt.columns) == getRowKey(tt.b, tt.columns); equal != tt.equal {
+                t.Errorf("getRowKey() equal = %v, want %v", equal, tt.equal)
+            }
+        })
+    }
+}
@@ -0,0 +1,397 @@
+package cmd
+
+import (
+    "bufio"
+    "encoding/hex"
+    "fmt"
+    "io"
+    "regexp"
+    "strings"
+
+    "github.com/asaskevich/govalidator"
+    "github.com/camry/g/gutil"
+    "github.com/glebarez/sqlite"
+    "gorm.io/gorm"
+    "gorm.io/gorm/logger"
+)
+
+// SyncTableName 增量同步水位表。
+const SyncTableName = "mysql2sqlite_sync"
+
+var createSyncTableSql = fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (\n  `table_name` TEXT NOT NULL PRIMARY KEY,\n  `column_name` TEXT NOT NULL,\n  `watermark` TEXT NOT NULL,\n  `synced_at` TEXT NOT NULL\n)", SyncTableName)
+
+// Writer 单表输出。
+type Writer interface {
+    // Create 输出建表语句。
+    Create(tableName string, statements []string) error
+    // Insert 输出一批数据行。
+    Insert(tableName string, columns []*MySQL2SQLiteColumn, rows [][]any) error
+    // Upsert 按 keys 冲突时更新，输出一批数据行。
+    Upsert(tableName string, columns []*MySQL2SQLiteColumn, keys []string, rows [][]any) error
+    // SetWatermark 记录增量同步水位。
+    SetWatermark(tableName string, columnName string, watermark string) error
+}
+
+// Output 转换结果输出。
+type Output interface {
+    Writer
+    // Begin 开始输出。
+    Begin() error
+    // End 结束输出。
+    End() error
+}
+
+// SqlWriter 输出 SQLite SQL 脚本。
+type SqlWriter struct {
+    w      *bufio.Writer
+    tables int
+}
+
+// NewSqlWriter 新建 SQL 脚本输出。
+func NewSqlWriter(w io.Writer) *SqlWriter {
+    return &SqlWriter{w: bufio.NewWriter(w)}
+}
+
+// Begin 开始输出。
+func (s *SqlWriter) Begin() error {
+    _, err := fmt.Fprint(s.w, "PRAGMA foreign_keys = false;\n\n")
+    return err
+}
+
+// Create 输出建表语句。
+func (s *SqlWriter) Create(tableName string, statements []string) error {
+    if s.tables > 0 {
+        if _, err := fmt.Fprintln(s.w); err != nil {
+            return err
+        }
+    }
+    s.tables++
+
+    for _, statement := range statements {
+        if _, err := fmt.Fprintln(s.w, statement); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Insert 输出一批数据行。
+func (s *SqlWriter) Insert(tableName string, columns []*MySQL2SQLiteColumn, rows [][]any) error {
+    var ks, kv []string
+    for _, column := range columns {
+        ks = append(ks, fmt.Sprintf("`%s`", column.SQLiteColumnName))
+    }
+    for _, row := range rows {
+        var vs []string
+        for i, column := range columns {
+            vs = append(vs, getLiteral(column, row[i]))
+        }
+        kv = append(kv, fmt.Sprintf("(%s)", strings.Join(vs, ",")))
+    }
+
+    _, err := fmt.Fprintf(s.w, "INSERT INTO `%s` (%s) VALUES %s;\n",
+        tableName,
+        strings.Join(ks, ","),
+        strings.Join(kv, ","),
+    )
+    return err
+}
+
+// Upsert 按 keys 冲突时更新，输出一批数据行。
+func (s *SqlWriter) Upsert(tableName string, columns []*MySQL2SQLiteColumn, keys []string, rows [][]any) error {
+    var kv []string
+    for _, row := range rows {
+        var vs []string
+        for i, column := range columns {
+            vs = append(vs, getLiteral(column, row[i]))
+        }
+        kv = append(kv, fmt.Sprintf("(%s)", strings.Join(vs, ",")))
+    }
+
+    _, err := fmt.Fprintf(s.w, "%s;\n", getUpsertSql(tableName, columns, keys, strings.Join(kv, ",")))
+    return err
+}
+
+// SetWatermark 记录增量同步水位。
+func (s *SqlWriter) SetWatermark(tableName string, columnName string, watermark string) error {
+    _, err := fmt.Fprintf(s.w, "%s;\n%s;\n", createSyncTableSql, getWatermarkSql(fmt.Sprintf("(%s,%s,%s,CURRENT_TIMESTAMP)",
+        quoteString(tableName),
+        quoteString(columnName),
+        quoteString(watermark),
+    )))
+    return err
+}
+
+// End 结束输出。
+func (s *SqlWriter) End() error {
+    if _, err := fmt.Fprint(s.w, "\nPRAGMA foreign_keys = true;\n"); err != nil {
+        return err
+    }
+    return s.w.Flush()
+}
+
+// SQLiteWriter 直接写入 SQLite 数据库文件。
+type SQLiteWriter struct {
+    db *gorm.DB
+}
+
+// NewSQLiteWriter 新建 SQLite 数据库输出。
+func NewSQLiteWriter(path string) (*SQLiteWriter, error) {
+    db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+        SkipDefaultTransaction: true,
+        PrepareStmt:            true,
+        Logger:                 logger.Default.LogMode(logger.Silent),
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    // PRAGMA 仅对当前连接生效，限制为单连接。
+    sqlDb, err := db.DB()
+    if err != nil {
+        return nil, err
+    }
+    sqlDb.SetMaxOpenConns(1)
+
+    return &SQLiteWriter{db: db}, nil
+}
+
+// validateSql 在内存 SQLite 数据库中执行语句，检查 SQLite 能否执行。(CREATE VIEW 只检查语法，不检查引用的表)
+func validateSql(statement string) error {
+    s, err := NewSQLiteWriter(":memory:")
+    if err != nil {
+        return err
+    }
+    defer s.End()
+
+    return s.db.Exec(statement).Error
+}
+
+// Begin 开始输出。
+func (s *SQLiteWriter) Begin() error {
+    return s.db.Exec("PRAGMA foreign_keys = false").Error
+}
+
+// Create 执行建表语句。
+func (s *SQLiteWriter) Create(tableName string, statements []string) error {
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        for _, statement := range statements {
+            if err := tx.Exec(statement).Error; err != nil {
+                return fmt.Errorf("表 `%s` 建表失败: %w", tableName, err)
+            }
+        }
+        return nil
+    })
+}
+
+// Insert 使用预处理语句在事务中写入一批数据行。
+func (s *SQLiteWriter) Insert(tableName string, columns []*MySQL2SQLiteColumn, rows [][]any) error {
+    var ks, ps []string
+    for _, column := range columns {
+        ks = append(ks, fmt.Sprintf("`%s`", column.SQLiteColumnName))
+        ps = append(ps, "?")
+    }
+    insertSql := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)",
+        tableName,
+        strings.Join(ks, ","),
+        strings.Join(ps, ","),
+    )
+
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        for _, row := range rows {
+            if err := tx.Exec(insertSql, row...).Error; err != nil {
+                return fmt.Errorf("表 `%s` 写入失败: %w", tableName, err)
+            }
+        }
+        return nil
+    })
+}
+
+// Upsert 使用预处理语句在事务中按 keys 冲突时更新一批数据行。
+func (s *SQLiteWriter) Upsert(tableName string, columns []*MySQL2SQLiteColumn, keys []string, rows [][]any) error {
+    var ps []string
+    for range columns {
+        ps = append(ps, "?")
+    }
+    upsertSql := getUpsertSql(tableName, columns, keys, fmt.Sprintf("(%s)", strings.Join(ps, ",")))
+
+    return s.db.Transaction(func(tx *gorm.DB) error {
+        for _, row := range rows {
+            if err := tx.Exec(upsertSql, row...).Error; err != nil {
+                return fmt.Errorf("表 `%s` 写入失败: %w", tableName, err)
+            }
+        }
+        return nil
+    })
+}
+
+// Watermarks 读取已存在的表的增量同步水位。
+func (s *SQLiteWriter) Watermarks() (map[string]string, error) {
+    var syncData []struct {
+        TableName string
+        Watermark string
+    }
+
+    if err := s.db.Exec(createSyncTableSql).Error; err != nil {
+        return nil, err
+    }
+    err := s.db.Raw(fmt.Sprintf("SELECT s.`table_name`, s.`watermark` FROM `%s` s JOIN sqlite_master m ON m.`type` = 'table' AND m.`name` = s.`table_name`", SyncTableName)).
+        Scan(&syncData).Error
+    if err != nil {
+        return nil, err
+    }
+
+    watermarks := make(map[string]string, len(syncData))
+    for _, sync := range syncData {
+        watermarks[sync.TableName] = sync.Watermark
+    }
+    return watermarks, nil
+}
+
+// IndexTables 读取已存在的索引名及所属的表。
+func (s *SQLiteWriter) IndexTables() (map[string]string, error) {
+    var indexData []struct {
+        Name    string
+        TblName string
+    }
+
+    err := s.db.Raw("SELECT `name`, `tbl_name` FROM sqlite_master WHERE `type` = 'index' AND `sql` IS NOT NULL").Scan(&indexData).Error
+    if err != nil {
+        return nil, err
+    }
+
+    indexTables := make(map[string]string, len(indexData))
+    for _, index := range indexData {
+        indexTables[index.Name] = index.TblName
+    }
+    return indexTables, nil
+}
+
+// SetWatermark 记录增量同步水位。
+func (s *SQLiteWriter) SetWatermark(tableName string, columnName string, watermark string) error {
+    if err := s.db.Exec(createSyncTableSql).Error; err != nil {
+        return err
+    }
+    return s.db.Exec(getWatermarkSql("(?,?,?,CURRENT_TIMESTAMP)"), tableName, columnName, watermark).Error
+}
+
+// End 结束输出。
+func (s *SQLiteWriter) End() error {
+    if err := s.db.Exec("PRAGMA foreign_keys = true").Error; err != nil {
+        return err
+    }
+    sqlDb, err := s.db.DB()
+    if err != nil {
+        return err
+    }
+    return sqlDb.Close()
+}
+
+// streamWriter 单表流式输出，数据批次经有界通道交由主协程按表名顺序写入目标输出。
+type streamWriter struct {
+    ops chan func(w Writer) error
+}
+
+// newStreamWriter 新建单表流式输出。
+func newStreamWriter() *streamWriter {
+    return &streamWriter{ops: make(chan func(w Writer) error, 2)}
+}
+
+// Create 发送建表语句。
+func (s *streamWriter) Create(tableName string, statements []string) error {
+    s.ops <- func(w Writer) error {
+        return w.Create(tableName, statements)
+    }
+    return nil
+}
+
+// Insert 发送一批数据行。
+func (s *streamWriter) Insert(tableName string, columns []*MySQL2SQLiteColumn, rows [][]any) error {
+    s.ops <- func(w Writer) error {
+        return w.Insert(tableName, columns, rows)
+    }
+    return nil
+}
+
+// Upsert 发送一批按 keys 冲突时更新的数据行。
+func (s *streamWriter) Upsert(tableName string, columns []*MySQL2SQLiteColumn, keys []string, rows [][]any) error {
+    s.ops <- func(w Writer) error {
+        return w.Upsert(tableName, columns, keys, rows)
+    }
+    return nil
+}
+
+// SetWatermark 发送增量同步水位。
+func (s *streamWriter) SetWatermark(tableName string, columnName string, watermark string) error {
+    s.ops <- func(w Writer) error {
+        return w.SetWatermark(tableName, columnName, watermark)
+    }
+    return nil
+}
+
+// close 结束发送。
+func (s *streamWriter) close() {
+    close(s.ops)
+}
+
+// flush 写入目标输出，直到单表转换结束。
+func (s *streamWriter) flush(w Writer) error {
+    for op := range s.ops {
+        if err := op(w); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// getUpsertSql SQLite INSERT ... ON CONFLICT DO UPDATE 语句。
+func getUpsertSql(tableName string, columns []*MySQL2SQLiteColumn, keys []string, values string) string {
+    var ks, cs, us []string
+    for _, column := range columns {
+        cs = append(cs, fmt.Sprintf("`%s`", column.SQLiteColumnName))
+        if !gutil.InArray(column.SQLiteColumnName, keys) {
+            us = append(us, fmt.Sprintf("`%s` = excluded.`%s`", column.SQLiteColumnName, column.SQLiteColumnName))
+        }
+    }
+    for _, key := range keys {
+        ks = append(ks, fmt.Sprintf("`%s`", key))
+    }
+
+    update := "DO NOTHING"
+    if len(us) > 0 {
+        update = fmt.Sprintf("DO UPDATE SET %s", strings.Join(us, ", "))
+    }
+    return fmt.Sprintf("INSERT INTO `%s` (%s) VALUES %s ON CONFLICT (%s) %s",
+        tableName,
+        strings.Join(cs, ","),
+        values,
+        strings.Join(ks, ","),
+        update,
+    )
+}
+
+// getWatermarkSql 增量同步水位写入语句。
+func getWatermarkSql(values string) string {
+    return fmt.Sprintf("INSERT INTO `%s` (`table_name`,`column_name`,`watermark`,`synced_at`) VALUES %s ON CONFLICT (`table_name`) DO UPDATE SET `column_name` = excluded.`column_name`, `watermark` = excluded.`watermark`, `synced_at` = excluded.`synced_at`", SyncTableName, values)
+}
+
+var numericPattern = regexp.MustCompile(`^[-+]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+
+// getLiteral SQLite 字面量。
+func getLiteral(column *MySQL2SQLiteColumn, value any) string {
+    if value == nil {
+        return "NULL"
+    }
+    switch column.SQLiteDataType {
+    case "INTEGER", "REAL":
+        // 数值原样输出，脱敏规则生成的文本（fixed、hash 等）按字符串输出。
+        if v := govalidator.ToString(value); numericPattern.MatchString(v) {
+            return v
+        }
+    case "BLOB":
+        if bs, ok := value.([]byte); ok {
+            return fmt.Sprintf("X'%s'", strings.ToUpper(hex.EncodeToString(bs)))
+        }
+    }
+    return fmt.Sprintf("'%s'", strings.ReplaceAll(govalidator.ToString(value), "'", "''"))
+}
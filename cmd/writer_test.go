@@ -0,0 +1,204 @@
+package cmd
+
+import (
+    "bytes"
+    "path/filepath"
+    "reflect"
+    "testing"
+)
+
+func TestWriterBinaryRoundTrip(t *testing.T) {
+    columns := []*MySQL2SQLiteColumn{
+        {ColumnName: "id", SQLiteColumnName: "id", DataType: "INT", SQLiteDataType: "INTEGER"},
+        {ColumnName: "name", SQLiteColumnName: "name", DataType: "VARCHAR", SQLiteDataType: "TEXT"},
+        {ColumnName: "data", SQLiteColumnName: "data", DataType: "VARBINARY", SQLiteDataType: "BLOB"},
+    }
+    statements := []string{"CREATE TABLE `bin` (`id` INTEGER NOT NULL PRIMARY KEY, `name` TEXT, `data` BLOB);"}
+    values := [][]byte{
+        []byte("it's"),
+        {0x00, 'a', 0x00},
+        {0xff, 0xfe, 0x00, '\''},
+        {0xc3, 0x28, 0xe2, 0x82},
+        {},
+    }
+    var rows [][]any
+    for i, value := range values {
+        rows = append(rows, []any{int64(i + 1), "it's", value})
+    }
+
+    tests := []struct {
+        name  string
+        write func(t *testing.T, path string)
+    }{
+        {"sql", func(t *testing.T, path string) {
+            var buf bytes.Buffer
+            w := NewSqlWriter(&buf)
+            if err := w.Begin(); err != nil {
+                t.Fatal(err)
+            }
+            if err := w.Create("bin", statements); err != nil {
+                t.Fatal(err)
+            }
+            if err := w.Insert("bin", columns, rows); err != nil {
+                t.Fatal(err)
+            }
+            if err := w.End(); err != nil {
+                t.Fatal(err)
+            }
+
+            s, err := NewSQLiteWriter(path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer s.End()
+            sqlDb, err := s.db.DB()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if _, err = sqlDb.Exec(buf.String()); err != nil {
+                t.Fatalf("执行 SQL 失败: %v\n%s", err, buf.String())
+            }
+        }},
+        {"sqlite", func(t *testing.T, path string) {
+            s, err := NewSQLiteWriter(path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer s.End()
+            if err = s.Begin(); err != nil {
+                t.Fatal(err)
+            }
+            if err = s.Create("bin", statements); err != nil {
+                t.Fatal(err)
+            }
+            if err = s.Insert("bin", columns, rows); err != nil {
+                t.Fatal(err)
+            }
+        }},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "bin.db")
+            tt.write(t, path)
+
+            s, err := NewSQLiteWriter(path)
+            if err != nil {
+                t.Fatal(err)
+            }
+            defer s.End()
+
+            var got []struct {
+                Name     string
+                Data     []byte
+                DataType string
+            }
+            if err = s.db.Raw("SELECT `name`, `data`, typeof(`data`) AS `data_type` FROM `bin` ORDER BY `id`").Scan(&got).Error; err != nil {
+                t.Fatal(err)
+            }
+            if len(got) != len(values) {
+                t.Fatalf("rows = %d, want %d", len(got), len(values))
+            }
+            for i, value := range values {
+                if got[i].Name != "it's" {
+                    t.Errorf("row %d name = %q, want %q", i+1, got[i].Name, "it's")
+                }
+                if !bytes.Equal(got[i].Data, value) || got[i].DataType != "blob" {
+                    t.Errorf("row %d data = %x (%s), want %x (blob)", i+1, got[i].Data, got[i].DataType, value)
+                }
+            }
+        })
+    }
+}
+
+func TestWatermarks(t *testing.T) {
+    columns := []*MySQL2SQLiteColumn{
+        {ColumnName: "id", SQLiteColumnName: "id", DataType: "INT", SQLiteDataType: "INTEGER"},
+        {ColumnName: "name", SQLiteColumnName: "name", DataType: "VARCHAR", SQLiteDataType: "TEXT"},
+    }
+
+    var buf bytes.Buffer
+    w := NewSqlWriter(&buf)
+    for _, err := range []error{
+        w.Begin(),
+        w.SetWatermark("player", "updated_at", "2024-01-01 00:00:00"),
+        w.Upsert("player", columns, []string{"id"}, [][]any{{int64(1), "b"}, {int64(2), "c"}}),
+        w.SetWatermark("player", "updated_at", "2024-01-02 00:00:00"),
+        w.SetWatermark("gone", "id", "9"),
+        w.End(),
+    } {
+        if err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    s, err := NewSQLiteWriter(filepath.Join(t.TempDir(), "sync.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer s.End()
+    if err = s.Create("player", []string{"CREATE TABLE `player` (`id` INTEGER NOT NULL PRIMARY KEY, `name` TEXT);"}); err != nil {
+        t.Fatal(err)
+    }
+    if err = s.Insert("player", columns, [][]any{{int64(1), "a"}}); err != nil {
+        t.Fatal(err)
+    }
+    sqlDb, err := s.db.DB()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if _, err = sqlDb.Exec(buf.String()); err != nil {
+        t.Fatalf("执行 SQL 失败: %v\n%s", err, buf.String())
+    }
+
+    // 已删除的表不返回水位。
+    watermarks, err := s.Watermarks()
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(watermarks) != 1 || watermarks["player"] != "2024-01-02 00:00:00" {
+        t.Errorf("Watermarks() = %v", watermarks)
+    }
+
+    if err = s.SetWatermark("player", "updated_at", "2024-01-03 00:00:00"); err != nil {
+        t.Fatal(err)
+    }
+    if watermarks, err = s.Watermarks(); err != nil || watermarks["player"] != "2024-01-03 00:00:00" {
+        t.Errorf("Watermarks() = %v, %v", watermarks, err)
+    }
+
+    var names []string
+    if err = s.db.Raw("SELECT `name` FROM `player` ORDER BY `id`").Scan(&names).Error; err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(names, []string{"b", "c"}) {
+        t.Errorf("names = %v, want [b c]", names)
+    }
+}
+
+func TestGetLiteral(t *testing.T) {
+    tests := []struct {
+        sqliteDataType string
+        value          any
+        want           string
+    }{
+        {"INTEGER", nil, "NULL"},
+        {"INTEGER", int64(-42), "-42"},
+        {"REAL", 1.5, "1.5"},
+        {"REAL", "1e-07", "1e-07"},
+        {"REAL", "12.340", "12.340"},
+        {"INTEGER", "****", "'****'"},
+        {"REAL", "it's", "'it''s'"},
+        {"BLOB", []byte{0x00, 0xff}, "X'00FF'"},
+        {"BLOB", "x", "'x'"},
+        {"TEXT", "a'b", "'a''b'"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.want, func(t *testing.T) {
+            if got := getLiteral(&MySQL2SQLiteColumn{SQLiteDataType: tt.sqliteDataType}, tt.value); got != tt.want {
+                t.Errorf("getLiteral() = %s, want %s", got, tt.want)
+            }
+        })
+    }
+}